@@ -1,49 +1,492 @@
 package main
 
 import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/schigh/tools/pkg/bcrypthash"
+	"github.com/schigh/tools/pkg/mac"
+	"github.com/schigh/tools/pkg/secret"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const usage = `usage:
+  bcrypt [secret flags] [-cost 4-31|min|max|default]
+  bcrypt verify -hash '$2a$...' [secret flags]
+  bcrypt calibrate [-target 250ms] [-samples n]
+  bcrypt inspect [-json] [hash...]
+  bcrypt needs-rehash [-min-cost 12] [-csv [-header] [-column n|name]] [file...]
+
+the secret is read from -secret-file, -secret-env, standard input when it is
+piped, or a prompt that does not echo. -secret still works but exposes the
+secret to shell history and other users' ps.
+
+peppered mode is on when one of -pepper-file, -pepper-env, -pepper-hex or
+-pepper-base64 is given. the secret is replaced with
+base64(HMAC-SHA256(pepper, secret)) before bcrypt, so secrets longer than 72
+bytes are not cut short. the hash does not record that it is peppered; verify
+with the same pepper. -prefix and -suffix are the older, unkeyed form of this
+and cannot be combined with it.
+
+verify exits 0 if the secret matches, 1 if it does not and 2 if it could not
+be checked: the hash is malformed, or a flag, the secret or the pepper is
+invalid. inspect reads hashes from standard input when none are given.
+needs-rehash prints the lines, or CSV rows, whose hash is below -min-cost or
+malformed, and exits 1 if there are any.`
+
+// Exit codes for verify. exitMalformed is also used for usage and read
+// errors, as flag does, so that exitMismatch only ever means the secret is
+// wrong.
+const (
+	exitMatch     = 0
+	exitMismatch  = 1
+	exitMalformed = 2
+)
+
+// defaultPolicyCost is the minimum cost needs-rehash enforces when -min-cost
+// is omitted.
+const defaultPolicyCost = 12
+
+// defaultTarget is the calibration target when -target is omitted. A quarter
+// second is slow enough to hurt offline guessing and fast enough for a login
+// under load.
+const defaultTarget = 250 * time.Millisecond
+
 var (
-	prefix string
-	secret string
-	suffix string
-	cost   string
+	prefix     string
+	secretArg  string
+	secretFile string
+	secretEnv  string
+	suffix     string
+	trim       bool
+	cost       string
+	hashed     string
+	target     time.Duration
+	samples    int
+	asJSON     bool
+	minCost    int
+	asCSV      bool
+	header     bool
+	column     string
+	pepperFile string
+	pepperEnv  string
+	pepperHex  string
+	pepperB64  string
 )
 
 func main() {
-	flag.StringVar(&secret, "secret", "", "the secret to be encrypted")
-	flag.StringVar(&prefix, "prefix", "", "prefix applied to secret before encryption")
-	flag.StringVar(&suffix, "suffix", "", "suffix applied to secret before encryption")
-	flag.StringVar(&cost, "cost", "default", "encryption cost (min|max|default)")
-	flag.Parse()
-
-	if secret == "" {
-		log.Fatalln("secret is required")
-	}
-
-	var bCost int
-	switch cost {
-	case "min":
-		bCost = bcrypt.MinCost
-	case "max":
-		bCost = bcrypt.MaxCost
-	case "default":
-		bCost = bcrypt.DefaultCost
+	cmd := ""
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+
+	fs := flag.NewFlagSet("bcrypt", flag.ExitOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+
+	switch cmd {
+	case "":
+		secretFlags(fs)
+		fs.StringVar(&cost, "cost", "default", fmt.Sprintf("encryption cost, %d-%d (or min|max|default)", bcrypt.MinCost, bcrypt.MaxCost))
+		_ = fs.Parse(args)
+		generate()
+	case "verify":
+		secretFlags(fs)
+		fs.StringVar(&hashed, "hash", "", "the bcrypt hash to check the secret against")
+		_ = fs.Parse(args)
+		os.Exit(verify())
+	case "calibrate":
+		fs.DurationVar(&target, "target", defaultTarget, "the longest a single hash may take")
+		fs.IntVar(&samples, "samples", 3, "hashes timed at each cost. the median is used")
+		_ = fs.Parse(args)
+		calibrate()
+	case "inspect":
+		fs.BoolVar(&asJSON, "json", false, "print JSON")
+		_ = fs.Parse(args)
+		os.Exit(inspect(fs.Args()))
+	case "needs-rehash":
+		fs.IntVar(&minCost, "min-cost", defaultPolicyCost, "the lowest acceptable cost")
+		fs.BoolVar(&asCSV, "csv", false, "read CSV and check one column")
+		fs.BoolVar(&header, "header", false, "the first CSV row is a header, which is always printed")
+		fs.StringVar(&column, "column", "1", "the CSV column holding the hash, by 1-based number or, with -header, name")
+		_ = fs.Parse(args)
+		os.Exit(needsRehash(fs.Args()))
 	default:
-		log.Fatalf("'%s' is an invalid cost. Use 'min', 'max' or omit the flag to use default", cost)
+		log.Fatalf("unknown command '%s'\n%s", cmd, usage)
 	}
+}
+
+// secretFlags registers the flags that describe the secret, which generation
+// and verification must read identically.
+func secretFlags(fs *flag.FlagSet) {
+	fs.StringVar(&secretArg, "secret", "", "the secret to be encrypted. prefer the other sources")
+	fs.StringVar(&secretFile, "secret-file", "", "read the secret from this file")
+	fs.StringVar(&secretEnv, "secret-env", "", "read the secret from this environment variable")
+	fs.StringVar(&prefix, "prefix", "", "prefix applied to secret before encryption")
+	fs.StringVar(&suffix, "suffix", "", "suffix applied to secret before encryption")
+	fs.BoolVar(&trim, "trim", false, "remove leading and trailing whitespace from prefix+secret+suffix, as older versions always did")
+	fs.StringVar(&pepperFile, "pepper-file", "", "peppered mode: read the pepper from this file, byte for byte")
+	fs.StringVar(&pepperEnv, "pepper-env", "", "peppered mode: read the pepper from this environment variable")
+	fs.StringVar(&pepperHex, "pepper-hex", "", "peppered mode: hex encoded pepper")
+	fs.StringVar(&pepperB64, "pepper-base64", "", "peppered mode: base64 encoded pepper")
+}
 
-	raw := []byte(strings.TrimSpace(prefix + secret + suffix))
-	hash, err := bcrypt.GenerateFromPassword(raw, bCost)
+func generate() {
+	bCost, err := bcrypthash.ParseCost(cost)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	s, err := raw(true)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err := bcrypthash.CheckLength(s); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "warning: %v. use a -pepper flag to hash all of it\n", err)
+	}
+	hash, err := bcrypt.GenerateFromPassword(s, bCost)
 	if err != nil {
 		log.Fatalln(err)
 	}
 
 	fmt.Println(string(hash))
 }
+
+// calibrate times GenerateFromPassword at increasing costs and recommends
+// the highest one that stays under the target. Each step doubles the work,
+// so timing stops at the first cost over the target.
+func calibrate() {
+	if target <= 0 {
+		log.Fatalf("'%s' is an invalid target. it must be positive", target)
+	}
+	if samples < 1 {
+		log.Fatalf("'%d' is an invalid number of samples. it must be at least 1", samples)
+	}
+
+	password := []byte("calibration password")
+	recommended := 0
+	fmt.Printf("cost  median (of %d)\n", samples)
+	for c := bcrypt.MinCost; c <= bcrypt.MaxCost; c++ {
+		times := make([]time.Duration, samples)
+		for i := range times {
+			start := time.Now()
+			if _, err := bcrypt.GenerateFromPassword(password, c); err != nil {
+				log.Fatalln(err)
+			}
+			times[i] = time.Since(start)
+		}
+		sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+		median := times[len(times)/2]
+
+		mark := ""
+		if median > target {
+			mark = "  over target"
+		}
+		fmt.Printf("%4d  %s%s\n", c, median.Round(time.Microsecond), mark)
+		if median > target {
+			break
+		}
+		recommended = c
+	}
+
+	if recommended == 0 {
+		fmt.Printf("\neven cost %d takes longer than %s on this machine. use -cost %d and consider a longer target\n", bcrypt.MinCost, target, bcrypt.MinCost)
+		return
+	}
+	fmt.Printf("\nrecommended: -cost %d (target %s)\n", recommended, target)
+	if recommended < bcrypt.DefaultCost {
+		fmt.Printf("warning: this is below the library default of %d\n", bcrypt.DefaultCost)
+	}
+}
+
+// inspect prints the version, cost and salt of each hash and returns 1 if any
+// could not be parsed.
+func inspect(hashes []string) int {
+	if len(hashes) == 0 {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				hashes = append(hashes, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	type result struct {
+		Hash string `json:"hash"`
+		*bcrypthash.Info
+		Error string `json:"error,omitempty"`
+	}
+	code := 0
+	results := make([]result, len(hashes))
+	for i, h := range hashes {
+		results[i].Hash = h
+		info, err := bcrypthash.Inspect(h)
+		if err != nil {
+			results[i].Error = err.Error()
+			code = 1
+			continue
+		}
+		results[i].Info = &info
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			log.Fatalln(err)
+		}
+		return code
+	}
+	for _, r := range results {
+		if r.Info == nil {
+			fmt.Printf("%s: invalid: %s\n", r.Hash, r.Error)
+			continue
+		}
+		fmt.Printf("%s: version %s, cost %d, salt %s\n", r.Hash, r.Version, r.Cost, r.Salt)
+	}
+	return code
+}
+
+// needsRehash streams hashes, one per line or from a CSV column, and prints
+// the lines or rows that need rehashing. Malformed hashes are printed too,
+// since a migration has to deal with them, and are reported on stderr.
+func needsRehash(files []string) int {
+	if minCost < bcrypt.MinCost || minCost > bcrypt.MaxCost {
+		log.Fatalf("'%d' is an invalid minimum cost. use a number from %d to %d", minCost, bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	var checked, failed int
+	check := func(hash string) bool {
+		checked++
+		rehash, err := bcrypthash.NeedsRehash(strings.TrimSpace(hash), minCost)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "malformed hash %q: %v\n", hash, err)
+		}
+		if rehash || err != nil {
+			failed++
+			return true
+		}
+		return false
+	}
+
+	for _, f := range files {
+		r := os.Stdin
+		if f != "-" {
+			var err error
+			if r, err = os.Open(f); err != nil {
+				log.Fatalln(err)
+			}
+		}
+		var err error
+		if asCSV {
+			err = rehashCSV(r, check)
+		} else {
+			err = rehashLines(r, check)
+		}
+		if r != os.Stdin {
+			_ = r.Close()
+		}
+		if err != nil {
+			log.Fatalf("%s: %v", f, err)
+		}
+	}
+
+	_, _ = fmt.Fprintf(os.Stderr, "%d of %d hashes need rehashing to cost %d\n", failed, checked, minCost)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+func rehashLines(r io.Reader, check func(string) bool) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if check(line) {
+			fmt.Println(line)
+		}
+	}
+	return scanner.Err()
+}
+
+func rehashCSV(r io.Reader, check func(string) bool) error {
+	in := csv.NewReader(r)
+	in.FieldsPerRecord = -1
+	out := csv.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	idx := -1
+	if n, err := strconv.Atoi(column); err == nil {
+		if n < 1 {
+			return fmt.Errorf("'%s' is an invalid column. columns are numbered from 1", column)
+		}
+		idx = n - 1
+	}
+	if header {
+		row, err := in.Read()
+		if err != nil {
+			return err
+		}
+		if idx < 0 {
+			for i, name := range row {
+				if strings.EqualFold(strings.TrimSpace(name), column) {
+					idx = i
+				}
+			}
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	if idx < 0 {
+		return fmt.Errorf("there is no column named '%s'. use -header to name columns", column)
+	}
+
+	for {
+		row, err := in.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if idx >= len(row) {
+			return fmt.Errorf("line %d has no column %d", lineOf(in), idx+1)
+		}
+		if check(row[idx]) {
+			if err := out.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func lineOf(r *csv.Reader) int {
+	line, _ := r.FieldPos(0)
+	return line
+}
+
+// verify compares the secret with -hash and returns the exit code. A wrong
+// secret and a hash that could never match anything are reported
+// differently, since the second usually means the hash was truncated or
+// mangled on its way out of a database.
+func verify() int {
+	if hashed == "" {
+		_, _ = fmt.Fprintln(os.Stderr, "hash is required")
+		return exitMalformed
+	}
+	s, err := raw(false)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return exitMalformed
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(strings.TrimSpace(hashed)), s)
+	switch {
+	case err == nil:
+		fmt.Println("OK")
+		return exitMatch
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+		fmt.Println("FAILED")
+		return exitMismatch
+	case malformed(err):
+		_, _ = fmt.Fprintf(os.Stderr, "malformed hash: %v\n", err)
+		return exitMalformed
+	default:
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return exitMalformed
+	}
+}
+
+// malformed reports whether err means the hash itself is invalid.
+func malformed(err error) bool {
+	var (
+		prefixErr  bcrypt.InvalidHashPrefixError
+		costErr    bcrypt.InvalidCostError
+		versionErr bcrypt.HashVersionTooNewError
+	)
+	return errors.Is(err, bcrypt.ErrHashTooShort) ||
+		errors.As(err, &prefixErr) ||
+		errors.As(err, &costErr) ||
+		errors.As(err, &versionErr)
+}
+
+// raw reads the secret and applies the prefix and suffix, exactly as it is
+// hashed, so that verify accepts what generate produced. A new secret typed
+// at a prompt is asked for twice.
+func raw(confirm bool) ([]byte, error) {
+	s, err := secret.Read(secret.Options{
+		Value:   secretArg,
+		File:    secretFile,
+		Env:     secretEnv,
+		Prompt:  "Secret: ",
+		Confirm: confirm,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := prefix + string(s) + suffix
+	if trim {
+		out = strings.TrimSpace(out)
+	}
+
+	pepper, err := loadPepper()
+	if err != nil || pepper == nil {
+		return []byte(out), err
+	}
+	return bcrypthash.Pepper([]byte(out), pepper)
+}
+
+// loadPepper returns the pepper given by the -pepper flags, or nil if
+// peppered mode is off.
+func loadPepper() ([]byte, error) {
+	given := 0
+	for _, v := range []string{pepperFile, pepperEnv, pepperHex, pepperB64} {
+		if v != "" {
+			given++
+		}
+	}
+	switch {
+	case given == 0:
+		return nil, nil
+	case given > 1:
+		return nil, fmt.Errorf("only one of -pepper-file, -pepper-env, -pepper-hex or -pepper-base64 may be given")
+	case prefix != "" || suffix != "":
+		return nil, fmt.Errorf("-prefix and -suffix cannot be combined with a pepper")
+	}
+
+	switch {
+	case pepperFile != "":
+		return mac.KeyFromFile(pepperFile)
+	case pepperEnv != "":
+		return mac.KeyFromEnv(pepperEnv)
+	case pepperHex != "":
+		return mac.KeyFromHex(pepperHex)
+	default:
+		return mac.KeyFromBase64(pepperB64)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/schigh/tools/pkg/codes"
+)
+
+const usage = `usage:
+  codes generate -pattern <template> [-check none|luhn|damm] [-n count]
+  codes verify -pattern <template> [-check none|luhn|damm] <code>...`
+
+var (
+	pattern string
+	check   string
+	times   int
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fatal(fmt.Errorf(usage))
+	}
+
+	fs := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	fs.StringVar(&pattern, "pattern", "XXXX-XXXX-XXXX", "code template (9 digit, A upper, a lower, X upper+digit, x lower+digit, C crockford, [..] set, {n} repeat)")
+	fs.StringVar(&check, "check", "none", "check character algorithm (none|luhn|damm)")
+	fs.IntVar(&times, "n", 1, "number of unique codes to generate")
+	_ = fs.Parse(os.Args[2:])
+
+	checksum, err := codes.ParseChecksum(check)
+	if err != nil {
+		fatal(err)
+	}
+	tmpl, err := codes.Compile(pattern, checksum)
+	if err != nil {
+		fatal(err)
+	}
+
+	switch os.Args[1] {
+	case "generate":
+		batch, err := tmpl.GenerateBatch(times)
+		if err != nil {
+			fatal(err)
+		}
+		for _, c := range batch {
+			fmt.Println(c)
+		}
+	case "verify":
+		if fs.NArg() == 0 {
+			fatal(fmt.Errorf(usage))
+		}
+		failed := false
+		for _, c := range fs.Args() {
+			if err := tmpl.Verify(c); err != nil {
+				fmt.Printf("%s: FAILED (%v)\n", c, err)
+				failed = true
+				continue
+			}
+			fmt.Printf("%s: OK\n", c)
+		}
+		if failed {
+			os.Exit(1)
+		}
+	default:
+		fatal(fmt.Errorf("unknown command '%s'\n%s", os.Args[1], usage))
+	}
+}
+
+func fatal(err error) {
+	_, _ = fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
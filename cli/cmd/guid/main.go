@@ -1,25 +1,198 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/ntwrk1/guid"
+	"github.com/schigh/tools/pkg/guidprefix"
 )
 
 var (
-	prefix string
+	prefix   string
+	entity   string
+	registry string
+	times    int
+	random   bool
+	slug     bool
+	asJSON   bool
 )
 
+// details is the inspect output for a single GUID.
+type details struct {
+	GUID        guid.GUID `json:"guid"`
+	Prefix      string    `json:"prefix"`
+	Entity      string    `json:"entity,omitempty"`
+	Time        time.Time `json:"time"`
+	Fingerprint int32     `json:"fingerprint"`
+	Incr        int32     `json:"incr"`
+	Decr        int32     `json:"decr"`
+	Random      int32     `json:"random"`
+}
+
 func main() {
-	flag.StringVar(&prefix, "prefix", "", "byte prefix")
+	if len(os.Args) > 1 && os.Args[1] == "inspect" {
+		fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+		fs.BoolVar(&asJSON, "json", false, "output json")
+		fs.StringVar(&entity, "type", "", "fail unless every guid belongs to this registered entity")
+		fs.StringVar(&registry, "registry", os.Getenv("GUID_PREFIX_REGISTRY"), "prefix registry file")
+		_ = fs.Parse(os.Args[2:])
+		inspect(fs.Args())
+		return
+	}
+
+	flag.StringVar(&prefix, "prefix", "", "two byte prefix (0-9, a-z)")
+	flag.StringVar(&entity, "type", "", "registered entity whose prefix should be used")
+	flag.StringVar(&registry, "registry", os.Getenv("GUID_PREFIX_REGISTRY"), "prefix registry file")
+	flag.IntVar(&times, "n", 1, "number of guids to generate")
+	flag.BoolVar(&random, "random", false, "generate with guid.NewRandom, reporting generator errors instead of panicking")
+	flag.BoolVar(&slug, "slug", false, "output the guid slug instead of the full guid")
+	flag.BoolVar(&asJSON, "json", false, "output json")
 	flag.Parse()
 
-	if len(prefix) >= 2 {
-		guid.SetGlobalPrefixBytes(prefix[0], prefix[1])
+	if prefix != "" && entity != "" {
+		fatal(fmt.Errorf("-prefix and -type cannot be used together"))
+	}
+
+	var opts []guid.Option
+	switch {
+	case prefix != "":
+		p, err := guidprefix.ParsePrefix(prefix)
+		if err != nil {
+			fatal(err)
+		}
+		opts = append(opts, p.Option())
+	case entity != "":
+		reg, err := loadRegistry()
+		if err != nil {
+			fatal(err)
+		}
+		p, err := reg.Prefix(entity)
+		if err != nil {
+			fatal(err)
+		}
+		opts = append(opts, p.Option())
+	}
+
+	for i := 0; i < times; i++ {
+		g, err := generate(opts)
+		if err != nil {
+			fatal(err)
+		}
+		if err := output(g); err != nil {
+			fatal(err)
+		}
 	}
+}
+
+func generate(opts []guid.Option) (guid.GUID, error) {
+	if random {
+		return guid.NewRandom(opts...)
+	}
+	return guid.New(opts...), nil
+}
+
+func output(g guid.GUID) error {
+	switch {
+	case slug:
+		s := g.Slug()
+		if s == "" {
+			return fmt.Errorf("guid slugs are not implemented by this version of github.com/ntwrk1/guid")
+		}
+		if asJSON {
+			return printJSON(s)
+		}
+		fmt.Println(s)
+	case asJSON:
+		return printJSON(g)
+	default:
+		fmt.Println(g.String())
+	}
+	return nil
+}
 
-	g := guid.New()
+// inspect prints the components of each GUID passed on the command line.
+func inspect(args []string) {
+	if len(args) == 0 {
+		fatal(fmt.Errorf("usage: guid inspect [-json] [-type <entity>] <guid>..."))
+	}
+
+	var reg *guidprefix.Registry
+	if registry != "" || entity != "" {
+		r, err := loadRegistry()
+		if err != nil {
+			fatal(err)
+		}
+		reg = r
+	}
+
+	for i, arg := range args {
+		g, err := guid.ParseString(arg)
+		if err != nil {
+			fatal(err)
+		}
+		if entity != "" {
+			if err := reg.Validate(g, entity); err != nil {
+				fatal(err)
+			}
+		}
+		incr, decr := g.Counters()
+		d := details{
+			GUID:        g,
+			Prefix:      guidprefix.Of(g).String(),
+			Time:        g.Time().UTC(),
+			Fingerprint: g.Fingerprint(),
+			Incr:        incr,
+			Decr:        decr,
+			Random:      g.Random(),
+		}
+		if reg != nil {
+			d.Entity, _ = reg.Entity(g)
+		}
+
+		if asJSON {
+			if err := printJSON(d); err != nil {
+				fatal(err)
+			}
+			continue
+		}
+
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%-12s%s\n", "guid", d.GUID)
+		fmt.Printf("%-12s%s\n", "prefix", d.Prefix)
+		if d.Entity != "" {
+			fmt.Printf("%-12s%s\n", "entity", d.Entity)
+		}
+		fmt.Printf("%-12s%s\n", "time", d.Time.Format(time.RFC3339Nano))
+		fmt.Printf("%-12s%d\n", "fingerprint", d.Fingerprint)
+		fmt.Printf("%-12s%d\n", "incr", d.Incr)
+		fmt.Printf("%-12s%d\n", "decr", d.Decr)
+		fmt.Printf("%-12s%d\n", "random", d.Random)
+	}
+}
+
+func loadRegistry() (*guidprefix.Registry, error) {
+	if registry == "" {
+		return nil, fmt.Errorf("a prefix registry is required. use -registry or set GUID_PREFIX_REGISTRY")
+	}
+	return guidprefix.Load(registry)
+}
+
+func printJSON(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
 
-	fmt.Println(g.String())
+func fatal(err error) {
+	_, _ = fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
 }
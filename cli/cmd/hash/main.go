@@ -0,0 +1,9 @@
+package main
+
+import (
+	"github.com/schigh/tools/pkg/sumcmd"
+)
+
+func main() {
+	sumcmd.Main("hash", "")
+}
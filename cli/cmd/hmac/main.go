@@ -0,0 +1,174 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/schigh/tools/pkg/digest"
+	"github.com/schigh/tools/pkg/mac"
+)
+
+const usage = `usage:
+  hmac (-key-file f | -key-env NAME | -key-hex h | -key-base64 b) [flags] [file|glob|-]...
+  hmac (-key-...) -s string [flags]
+  hmac (-key-...) -verify mac [flags] [file|-|-s string]
+
+exactly one key source is required; there is no default key.
+-key-hex and -key-base64 put the key in your shell history and the process
+list, so prefer -key-file or -key-env outside of testing.`
+
+var (
+	keyFile   string
+	keyEnv    string
+	keyHex    string
+	keyBase64 string
+	digestAlg string
+	encoding  string
+	expect    string
+	literal   string
+)
+
+func main() {
+	fs := flag.NewFlagSet("hmac", flag.ExitOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&keyFile, "key-file", "", "read the key from this file, byte for byte")
+	fs.StringVar(&keyEnv, "key-env", "", "read the key from this environment variable")
+	fs.StringVar(&keyHex, "key-hex", "", "hex encoded key")
+	fs.StringVar(&keyBase64, "key-base64", "", "base64 encoded key")
+	fs.StringVar(&digestAlg, "digest", "sha256", "digest (md5|sha1|sha256|sha384|sha512)")
+	fs.StringVar(&encoding, "encoding", "hex", "output encoding (hex|base64|base64url)")
+	fs.StringVar(&expect, "verify", "", "compare against this hex or base64 MAC and print OK or FAILED")
+	fs.StringVar(&literal, "s", "", "sign this string instead of files")
+	_ = fs.Parse(os.Args[1:])
+
+	key, err := loadKey()
+	if err != nil {
+		fatal(err)
+	}
+	d, err := mac.ParseDigest(digestAlg)
+	if err != nil {
+		fatal(err)
+	}
+	enc, err := digest.ParseEncoding(encoding)
+	if err != nil {
+		fatal(err)
+	}
+	if enc != digest.Hex && enc != digest.Base64 && enc != digest.Base64URL {
+		fatal(fmt.Errorf("'%s' is an invalid encoding. use hex, base64 or base64url", encoding))
+	}
+
+	var want []byte
+	if expect != "" {
+		size, err := mac.Size(d)
+		if err != nil {
+			fatal(err)
+		}
+		if want, err = digest.Decode(expect, strings.ToLower(digestAlg), size); err != nil {
+			fatal(err)
+		}
+	}
+
+	literalSet := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "s" {
+			literalSet = true
+		}
+	})
+	if literalSet {
+		sum, err := mac.String(literal, key, d)
+		if err != nil {
+			fatal(err)
+		}
+		if !emit(sum, "", want, enc) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{digest.Stdin}
+	}
+	files, err = digest.Expand(files)
+	if err != nil {
+		fatal(err)
+	}
+
+	failed := false
+	for _, f := range files {
+		sum, err := mac.File(f, key, d)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "hmac: %v\n", err)
+			failed = true
+			continue
+		}
+		if !emit(sum, f, want, enc) {
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// loadKey returns the key from the one key flag that was given.
+func loadKey() ([]byte, error) {
+	given := 0
+	for _, v := range []string{keyFile, keyEnv, keyHex, keyBase64} {
+		if v != "" {
+			given++
+		}
+	}
+	if given != 1 {
+		return nil, fmt.Errorf("exactly one of -key-file, -key-env, -key-hex or -key-base64 is required\n%s", usage)
+	}
+
+	switch {
+	case keyFile != "":
+		return mac.KeyFromFile(keyFile)
+	case keyEnv != "":
+		return mac.KeyFromEnv(keyEnv)
+	case keyHex != "":
+		return mac.KeyFromHex(keyHex)
+	default:
+		return mac.KeyFromBase64(keyBase64)
+	}
+}
+
+// emit prints a MAC, or with -verify whether it matches, and reports whether
+// the input passed.
+func emit(sum []byte, name string, want []byte, enc digest.Encoding) bool {
+	if want != nil {
+		status := "OK"
+		ok := mac.Verify(sum, want)
+		if !ok {
+			status = "FAILED"
+		}
+		if name != "" {
+			status = name + ": " + status
+		}
+		fmt.Println(status)
+		return ok
+	}
+
+	s, err := digest.Encode(enc, digestAlg, sum)
+	if err != nil {
+		fatal(err)
+	}
+	if name == "" {
+		fmt.Println(s)
+		return true
+	}
+	fmt.Println(digest.Line(s, name))
+	return true
+}
+
+func fatal(err error) {
+	_, _ = fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/schigh/tools/pkg/bcrypthash"
+	"github.com/schigh/tools/pkg/htpasswd"
+	"github.com/schigh/tools/pkg/secret"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const usage = `usage:
+  htpasswd create [-force] [-cost n] [secret flags] <file> <user>
+  htpasswd add [-cost n] [secret flags] <file> <user>
+  htpasswd update [-cost n] [secret flags] <file> <user>
+  htpasswd delete <file> <user>
+  htpasswd verify [secret flags] <file> <user>
+  htpasswd list [-json] <file>
+
+the password is read from -secret-file, -secret-env, standard input when it
+is piped, or a prompt that does not echo. new hashes are always bcrypt.
+
+files holding md5 (apr1), sha1, crypt or other legacy hashes are not written
+and those hashes are not verified unless -allow-legacy is given. updating a
+legacy user replaces their hash with bcrypt.
+
+verify exits 0 if the password matches, 1 if it does not, 2 if it could not
+be checked, because the hash is in a legacy format or malformed or the file,
+password or flags could not be read, and 3 if there is no such user.`
+
+// Exit codes for verify. exitMalformed is also used for usage and read
+// errors, as flag does, so that exitMismatch only ever means the password is
+// wrong.
+const (
+	exitMatch      = 0
+	exitMismatch   = 1
+	exitMalformed  = 2
+	exitNoSuchUser = 3
+)
+
+var (
+	secretFile  string
+	secretEnv   string
+	cost        string
+	force       bool
+	allowLegacy bool
+	asJSON      bool
+)
+
+func main() {
+	cmd := ""
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+
+	fs := flag.NewFlagSet("htpasswd", flag.ExitOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+
+	switch cmd {
+	case "create", "add", "update":
+		secretFlags(fs)
+		fs.StringVar(&cost, "cost", "default", fmt.Sprintf("bcrypt cost, %d-%d (or min|max|default)", bcrypt.MinCost, bcrypt.MaxCost))
+		fs.BoolVar(&allowLegacy, "allow-legacy", false, "write the file even if other users have legacy hashes")
+		if cmd == "create" {
+			fs.BoolVar(&force, "force", false, "replace the file if it exists")
+		}
+		file, user, err := fileAndUser(fs, args)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		set(cmd, file, user)
+	case "delete":
+		fs.BoolVar(&allowLegacy, "allow-legacy", false, "write the file even if other users have legacy hashes")
+		file, user, err := fileAndUser(fs, args)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		remove(file, user)
+	case "verify":
+		secretFlags(fs)
+		fs.BoolVar(&allowLegacy, "allow-legacy", false, "verify apr1 and sha1 hashes too")
+		file, user, err := fileAndUser(fs, args)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitMalformed)
+		}
+		os.Exit(verify(file, user))
+	case "list":
+		fs.BoolVar(&asJSON, "json", false, "print JSON")
+		_ = fs.Parse(args)
+		if fs.NArg() != 1 {
+			fs.Usage()
+			os.Exit(2)
+		}
+		list(fs.Arg(0))
+	default:
+		log.Fatalf("unknown command '%s'\n%s", cmd, usage)
+	}
+}
+
+// secretFlags registers the flags that say where the password is read from.
+func secretFlags(fs *flag.FlagSet) {
+	fs.StringVar(&secretFile, "secret-file", "", "read the password from this file")
+	fs.StringVar(&secretEnv, "secret-env", "", "read the password from this environment variable")
+}
+
+// fileAndUser parses args and returns the file and user operands. A wrong
+// number of operands exits 2, as a bad flag does.
+func fileAndUser(fs *flag.FlagSet, args []string) (string, string, error) {
+	_ = fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	file, user := fs.Arg(0), fs.Arg(1)
+	return file, user, htpasswd.ValidUser(user)
+}
+
+func password(confirm bool) ([]byte, error) {
+	return secret.Read(secret.Options{
+		File:    secretFile,
+		Env:     secretEnv,
+		Prompt:  "Password: ",
+		Confirm: confirm,
+	})
+}
+
+// set creates, adds or updates user. The password is read and hashed before
+// the file is locked, so a slow prompt does not hold up other writers.
+func set(cmd, file, user string) {
+	c, err := bcrypthash.ParseCost(cost)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	s, err := password(true)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err := bcrypthash.CheckLength(s); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+	hash, err := htpasswd.Hash(s, c)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	flags := 0
+	if cmd == "create" {
+		flags = os.O_CREATE | os.O_EXCL
+		if force {
+			flags = os.O_CREATE | os.O_TRUNC
+		}
+	}
+	err = htpasswd.Update(file, flags, func(f *htpasswd.File) error {
+		_, exists := f.Lookup(user)
+		switch {
+		case cmd == "add" && exists:
+			return fmt.Errorf("user '%s' already exists. use update to change their password", user)
+		case cmd == "update" && !exists:
+			return fmt.Errorf("there is no user '%s'. use add to create them", user)
+		}
+		f.Set(user, hash)
+		return checkLegacy(f)
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func remove(file, user string) {
+	err := htpasswd.Update(file, 0, func(f *htpasswd.File) error {
+		if !f.Delete(user) {
+			return fmt.Errorf("there is no user '%s'", user)
+		}
+		return checkLegacy(f)
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// checkLegacy refuses to write a file that still has insecure hashes, unless
+// -allow-legacy is given.
+func checkLegacy(f *htpasswd.File) error {
+	insecure := f.Insecure()
+	if allowLegacy || len(insecure) == 0 {
+		return nil
+	}
+	users := make([]string, len(insecure))
+	for i, e := range insecure {
+		users[i] = fmt.Sprintf("%s (%s, line %d)", e.User, e.Algorithm, e.Line)
+	}
+	return fmt.Errorf("refusing to write a file with legacy hashes: %s. update those users or use -allow-legacy", strings.Join(users, ", "))
+}
+
+func verify(file, user string) int {
+	f, err := htpasswd.Read(file)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return exitMalformed
+	}
+	e, ok := f.Lookup(user)
+	if !ok {
+		_, _ = fmt.Fprintf(os.Stderr, "there is no user '%s'\n", user)
+		return exitNoSuchUser
+	}
+
+	s, err := password(false)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return exitMalformed
+	}
+
+	match, err := htpasswd.Verify(e.Hash, s, allowLegacy)
+	switch {
+	case errors.Is(err, htpasswd.ErrLegacy):
+		_, _ = fmt.Fprintf(os.Stderr, "%v. use -allow-legacy to check it anyway\n", err)
+		return exitMalformed
+	case err != nil:
+		_, _ = fmt.Fprintf(os.Stderr, "the hash for '%s' is malformed: %v\n", user, err)
+		return exitMalformed
+	case !match:
+		fmt.Println("FAILED")
+		return exitMismatch
+	}
+	fmt.Println("OK")
+	return exitMatch
+}
+
+// list prints each user with their hash algorithm and, for bcrypt, its
+// cost. Legacy hashes are marked so they stand out.
+func list(file string) {
+	f, err := htpasswd.Read(file)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	type row struct {
+		htpasswd.Entry
+		Cost   int  `json:"cost,omitempty"`
+		Secure bool `json:"secure"`
+	}
+	rows := make([]row, 0)
+	for _, e := range f.Entries() {
+		r := row{Entry: e, Secure: e.Algorithm.Secure()}
+		if e.Algorithm == htpasswd.Bcrypt {
+			if info, err := bcrypthash.Inspect(e.Hash); err == nil {
+				r.Cost = info.Cost
+			} else {
+				r.Secure = false
+			}
+		}
+		rows = append(rows, r)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(rows); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+	for _, r := range rows {
+		detail := string(r.Algorithm)
+		switch {
+		case r.Cost > 0:
+			detail = fmt.Sprintf("%s cost %d", r.Algorithm, r.Cost)
+		case r.Algorithm == htpasswd.Bcrypt:
+			detail = "bcrypt (malformed)"
+		}
+		if !r.Algorithm.Secure() {
+			detail += " (insecure)"
+		}
+		fmt.Printf("%s\t%s\n", r.User, detail)
+	}
+}
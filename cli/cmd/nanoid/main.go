@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/schigh/tools/pkg/nanoid"
+)
+
+var (
+	alphabet string
+	size     int
+	times    int
+)
+
+func main() {
+	flag.StringVar(&alphabet, "alphabet", nanoid.Alphabet, "characters used to build the id")
+	flag.IntVar(&size, "size", nanoid.DefaultSize, "length of the id")
+	flag.IntVar(&times, "n", 1, "number of ids to generate")
+	flag.Parse()
+
+	if times < 1 {
+		_, _ = fmt.Fprintf(os.Stderr, "'%d' is an invalid count. it must be at least 1\n", times)
+		os.Exit(1)
+	}
+
+	for i := 0; i < times; i++ {
+		id, err := generate()
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(id)
+	}
+}
+
+// generate uses the reference nanoid() path when the default alphabet is in
+// use so that the output is identical to the JavaScript library for the same
+// random bytes.
+func generate() (string, error) {
+	if alphabet == nanoid.Alphabet {
+		return nanoid.NewWithSize(size)
+	}
+	return nanoid.Custom(alphabet, size)
+}
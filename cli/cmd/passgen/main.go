@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/schigh/tools/pkg/passgen"
+)
+
+var (
+	policy     = passgen.DefaultPolicy()
+	phrase     = passgen.DefaultPassphrasePolicy()
+	passphrase bool
+	times      int
+	entropy    bool
+)
+
+func main() {
+	flag.IntVar(&policy.Length, "length", policy.Length, "password length")
+	flag.IntVar(&policy.MinLower, "min-lower", policy.MinLower, "minimum lowercase letters")
+	flag.IntVar(&policy.MinUpper, "min-upper", policy.MinUpper, "minimum uppercase letters")
+	flag.IntVar(&policy.MinDigits, "min-digits", policy.MinDigits, "minimum digits")
+	flag.IntVar(&policy.MinSymbols, "min-symbols", policy.MinSymbols, "minimum symbols")
+	flag.StringVar(&policy.Symbols, "symbols", policy.Symbols, "symbol characters. use an empty value to disable symbols")
+	flag.StringVar(&policy.Exclude, "exclude", "", "characters that must not appear")
+	flag.BoolVar(&policy.NoRepeats, "no-repeats", false, "forbid the same character twice in a row")
+
+	flag.BoolVar(&passphrase, "passphrase", false, "generate a diceware passphrase instead of a password")
+	flag.IntVar(&phrase.Words, "words", phrase.Words, "passphrase word count")
+	flag.StringVar(&phrase.Separator, "separator", phrase.Separator, "passphrase word separator")
+	flag.BoolVar(&phrase.Capitalize, "capitalize", false, "capitalize passphrase words")
+
+	flag.IntVar(&times, "n", 1, "number of passwords to generate")
+	flag.BoolVar(&entropy, "entropy", false, "print the estimated entropy to stderr")
+	flag.Parse()
+
+	if times < 1 {
+		fatal(fmt.Errorf("'%d' is an invalid count. it must be at least 1", times))
+	}
+	if policy.Symbols == "" {
+		policy.MinSymbols = 0
+	}
+
+	generate := func() (string, error) { return passgen.Password(policy) }
+	bits := func() (float64, error) { return policy.Entropy() }
+	if passphrase {
+		generate = func() (string, error) { return passgen.Passphrase(phrase) }
+		bits = func() (float64, error) { return phrase.Entropy(), nil }
+	}
+
+	for i := 0; i < times; i++ {
+		out, err := generate()
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Println(out)
+	}
+
+	if entropy {
+		b, err := bits()
+		if err != nil {
+			fatal(err)
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "entropy: %.1f bits\n", b)
+	}
+}
+
+func fatal(err error) {
+	_, _ = fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -1,30 +1,9 @@
 package main
 
 import (
-	"crypto/rand"
-	"fmt"
-	"os"
-
-	"github.com/schigh/str"
+	"github.com/schigh/tools/pkg/sumcmd"
 )
 
 func main() {
-	if len(os.Args) < 2 {
-		randomSHA1()
-		return
-	}
-
-	s := str.SHA1(os.Args[1])
-	fmt.Println(s)
-}
-
-func randomSHA1() {
-	b := make([]byte, 1024)
-	if _, err := rand.Read(b); err != nil {
-		panic(err)
-	}
-
-	s := str.SHA1(string(b))
-	fmt.Println(s)
+	sumcmd.Main("sha1", "sha1")
 }
-
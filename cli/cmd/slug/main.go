@@ -1,44 +1,268 @@
 package main
 
 import (
-	"crypto/rand"
+	"bufio"
+	"flag"
 	"fmt"
 	"math"
 	"os"
 	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/schigh/tools/pkg/randstr"
+	"github.com/schigh/tools/pkg/slugify"
 )
 
 const (
-	defaultLength = 8
-	charset       = `aAbBcCdDeEfFgGhHiIjJkKlLmMnNoOpPqQrRsStTuUvVwWxXyYzZ0123456789`
+	// defaultCollision and defaultCount size slugs so that a set of ten
+	// thousand has at most a one in a million chance of containing a
+	// duplicate. With the alnum charset that works out to 8 characters.
+	defaultCollision = 1e-6
+	defaultCount     = 1e4
+	// maxBits caps the size derived from -bits or -collision. Nothing we
+	// generate needs more entropy than a 512 bit key, and the cap keeps a
+	// typo such as -collision 1e-300 from producing megabytes of output. An
+	// explicit length is not capped, since it says exactly what to print.
+	maxBits = 512
 )
 
 var (
-	charsetLength = len(charset)
-	minLength     = float64(1)
-	maxLength     = float64(100)
+	charset     string
+	alphabet    string
+	noLookalike bool
+	times       int
+	bits        float64
+	collision   float64
+	count       string
+	explain     bool
+
+	text      string
+	separator string
+	maxLength int
+	stopwords bool
+	unique    bool
+	existing  string
 )
 
 func main() {
-	length := defaultLength
+	flag.StringVar(&charset, "charset", "alnum", "named character set ("+strings.Join(randstr.Names(), "|")+")")
+	flag.StringVar(&alphabet, "alphabet", "", "custom characters to use instead of -charset")
+	flag.BoolVar(&noLookalike, "no-lookalikes", false, "exclude easily confused characters ("+randstr.Lookalikes+")")
+	flag.IntVar(&times, "n", 1, "number of slugs to generate")
+	flag.Float64Var(&bits, "bits", 0, "size the slug to provide at least this many bits of entropy")
+	flag.Float64Var(&collision, "collision", 0, "size the slug so that -count slugs collide with at most this probability")
+	flag.StringVar(&count, "count", "", "number of slugs that must coexist, used with -collision (e.g. 10M, 1e9)")
+	flag.BoolVar(&explain, "explain", false, "print the entropy and collision probability of the chosen size to stderr")
+	flag.StringVar(&text, "text", "", "slugify this text instead of generating a random slug. use - to read lines from stdin")
+	flag.StringVar(&separator, "separator", slugify.DefaultSeparator, "word separator for -text")
+	flag.IntVar(&maxLength, "max-length", 0, "truncate -text slugs to this many characters at a word boundary")
+	flag.BoolVar(&stopwords, "stopwords", false, "remove common english words from -text slugs")
+	flag.BoolVar(&unique, "unique", false, "suffix -text slugs so that no two outputs are the same")
+	flag.StringVar(&existing, "existing", "", "file of slugs, one per line, that -text slugs must not collide with. implies -unique")
+	flag.Usage = func() {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "usage:\n  slug [flags] [length]\n  slug -text <text|-> [flags]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if text != "" {
+		if err := slugifyText(); err != nil {
+			fatal(err)
+		}
+		return
+	}
+
+	if times < 1 {
+		fatal(fmt.Errorf("'%d' is an invalid count. it must be at least 1", times))
+	}
+
+	chars := alphabet
+	if chars == "" {
+		a, err := randstr.Alphabet(charset)
+		if err != nil {
+			fatal(err)
+		}
+		chars = a
+	}
+	if noLookalike {
+		chars = randstr.ExcludeLookalikes(chars)
+	}
+
+	k := defaultCount
+	if count != "" {
+		v, err := parseCount(count)
+		if err != nil {
+			fatal(err)
+		}
+		k = v
+	}
+
+	length, err := size(chars, k)
+	if err != nil {
+		fatal(err)
+	}
+
+	if explain {
+		perChar := randstr.BitsPerChar(utf8.RuneCountInString(chars))
+		_, _ = fmt.Fprintf(os.Stderr, "%-10s%d characters (%.2f bits each)\n", "alphabet", utf8.RuneCountInString(chars), perChar)
+		_, _ = fmt.Fprintf(os.Stderr, "%-10s%d\n", "length", length)
+		_, _ = fmt.Fprintf(os.Stderr, "%-10s%.2f bits\n", "entropy", randstr.Entropy(chars, length))
+		_, _ = fmt.Fprintf(os.Stderr, "%-10s%.3g across %s slugs\n", "collision", randstr.CollisionProbability(chars, length, k), strconv.FormatFloat(k, 'f', -1, 64))
+	}
+
+	for i := 0; i < times; i++ {
+		out, err := randstr.String(chars, length)
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Println(out)
+	}
+}
+
+// size picks the slug length from, in order of precedence, an explicit length
+// argument, -bits, or -collision (falling back to the default collision
+// target). A derived length is limited to maxBits of entropy.
+func size(chars string, k float64) (int, error) {
+	if flag.NArg() > 0 {
+		if bits != 0 || collision != 0 {
+			return 0, fmt.Errorf("an explicit length cannot be combined with -bits or -collision")
+		}
+		v, err := strconv.Atoi(flag.Arg(0))
+		if err != nil || v < 1 {
+			return 0, fmt.Errorf("'%s' is an invalid length. it must be a positive integer", flag.Arg(0))
+		}
+		return v, nil
+	}
+
+	length, err := derivedSize(chars, k)
+	if err != nil {
+		return 0, err
+	}
+	if e := randstr.Entropy(chars, length); e > maxBits {
+		return 0, fmt.Errorf("a length of %d provides %.1f bits of entropy. the maximum is %d", length, e, maxBits)
+	}
+	return length, nil
+}
 
-	if len(os.Args) > 1 {
-		v, err := strconv.Atoi(os.Args[1])
-		if err == nil {
-			length = int(math.Max(math.Min(float64(v), maxLength), minLength))
+// derivedSize sizes the slug from -bits or -collision.
+func derivedSize(chars string, k float64) (int, error) {
+	if bits != 0 {
+		if collision != 0 {
+			return 0, fmt.Errorf("-bits and -collision cannot be used together")
 		}
+		if bits > maxBits {
+			return 0, fmt.Errorf("'%g' is an invalid number of bits. the maximum is %d", bits, maxBits)
+		}
+		return randstr.LengthForBits(chars, bits)
+	}
+
+	p := collision
+	if p == 0 {
+		p = defaultCollision
+	}
+	return randstr.LengthForCollision(chars, k, p)
+}
+
+// slugifyText prints a URL slug for -text, or for each line of stdin when
+// -text is "-".
+func slugifyText() error {
+	opts := slugify.Options{
+		Separator: separator,
+		MaxLength: maxLength,
+	}
+	if stopwords {
+		opts.Stopwords = slugify.EnglishStopwords
+	}
+	s, err := slugify.New(opts)
+	if err != nil {
+		return err
 	}
 
-	data := make([]byte, length)
-	if _, err := rand.Read(data); err != nil {
-		_, _ = fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	var taken map[string]bool
+	if unique || existing != "" {
+		taken = map[string]bool{}
+	}
+	if existing != "" {
+		lines, err := readLines(existing)
+		if err != nil {
+			return err
+		}
+		for _, l := range lines {
+			taken[l] = true
+		}
+	}
+
+	emit := func(in string) error {
+		if taken == nil {
+			fmt.Println(s.Slug(in))
+			return nil
+		}
+		out, err := s.Unique(in, taken)
+		if err != nil {
+			return err
+		}
+		taken[out] = true
+		fmt.Println(out)
+		return nil
 	}
 
-	out := make([]byte, length)
-	for i, b := range data {
-		out[i] = charset[int(b)%charsetLength]
+	if text != "-" {
+		return emit(text)
 	}
 
-	fmt.Println(string(out))
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		if err := emit(scanner.Text()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if l := strings.TrimSpace(scanner.Text()); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// parseCount accepts plain or scientific notation numbers as well as K, M, G
+// and B suffixes, so "10M", "1e7" and "10000000" are all equivalent.
+func parseCount(s string) (float64, error) {
+	multiplier := 1.0
+	trimmed := strings.TrimSpace(s)
+	if n := len(trimmed); n > 0 {
+		switch trimmed[n-1] {
+		case 'k', 'K':
+			multiplier = 1e3
+		case 'm', 'M':
+			multiplier = 1e6
+		case 'g', 'G', 'b', 'B':
+			multiplier = 1e9
+		}
+		if multiplier != 1 {
+			trimmed = trimmed[:n-1]
+		}
+	}
+	v, err := strconv.ParseFloat(trimmed, 64)
+	if err != nil || v <= 0 || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("'%s' is an invalid count", s)
+	}
+	return v * multiplier, nil
+}
+
+func fatal(err error) {
+	_, _ = fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
 }
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/schigh/tools/pkg/sqids"
+)
+
+const usage = `usage:
+  sqids encode [flags] <number>...
+  sqids decode [flags] <id>...
+
+the built in blocklist is a short english list, not the one other sqids
+implementations use, so encoded ids can differ from theirs. pass their list
+with -blocklist to match them. decoding is not affected.`
+
+var (
+	alphabet    string
+	minLength   int
+	blocklist   string
+	noBlocklist bool
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fatal(fmt.Errorf(usage))
+	}
+
+	fs := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	fs.StringVar(&alphabet, "alphabet", sqids.DefaultAlphabet, "characters used to build ids")
+	fs.IntVar(&minLength, "min-length", 0, "pad ids to at least this many characters")
+	fs.StringVar(&blocklist, "blocklist", "", "file of words, one per line, that must not appear in ids")
+	fs.BoolVar(&noBlocklist, "no-blocklist", false, "disable the blocklist")
+	_ = fs.Parse(os.Args[2:])
+
+	enc, err := encoder()
+	if err != nil {
+		fatal(err)
+	}
+
+	switch os.Args[1] {
+	case "encode":
+		encode(enc, fs.Args())
+	case "decode":
+		decode(enc, fs.Args())
+	default:
+		fatal(fmt.Errorf("unknown command '%s'\n%s", os.Args[1], usage))
+	}
+}
+
+// encode prints a single id for all numbers on the command line.
+func encode(enc *sqids.Encoder, args []string) {
+	if len(args) == 0 {
+		fatal(fmt.Errorf(usage))
+	}
+	numbers := make([]uint64, len(args))
+	for i, arg := range args {
+		n, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			fatal(fmt.Errorf("'%s' is not a non-negative integer", arg))
+		}
+		numbers[i] = n
+	}
+
+	id, err := enc.Encode(numbers)
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Println(id)
+}
+
+// decode prints the numbers for each id on the command line, one id per line.
+func decode(enc *sqids.Encoder, args []string) {
+	if len(args) == 0 {
+		fatal(fmt.Errorf(usage))
+	}
+	for _, id := range args {
+		numbers := enc.Decode(id)
+		if len(numbers) == 0 {
+			fatal(fmt.Errorf("'%s' is not a valid id for this alphabet", id))
+		}
+		out := make([]string, len(numbers))
+		for i, n := range numbers {
+			out[i] = strconv.FormatUint(n, 10)
+		}
+		fmt.Println(strings.Join(out, " "))
+	}
+}
+
+func encoder() (*sqids.Encoder, error) {
+	opts := sqids.Options{
+		Alphabet:  alphabet,
+		MinLength: minLength,
+	}
+	switch {
+	case noBlocklist:
+		opts.Blocklist = []string{}
+	case blocklist != "":
+		words, err := readWords(blocklist)
+		if err != nil {
+			return nil, err
+		}
+		opts.Blocklist = words
+	}
+
+	return sqids.New(opts)
+}
+
+func readWords(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	words := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if w := strings.TrimSpace(scanner.Text()); w != "" && !strings.HasPrefix(w, "#") {
+			words = append(words, w)
+		}
+	}
+
+	return words, scanner.Err()
+}
+
+func fatal(err error) {
+	_, _ = fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
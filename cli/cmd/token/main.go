@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/schigh/str"
+	"github.com/schigh/tools/pkg/token"
+)
+
+const usage = `usage:
+  token [-length n] [-charset chars] [-prefix p] [-n count]
+  token apikey -prefix p [-length n] [-crc32 default|c|k|k2|q] [-n count]
+  token verify -prefix p [-length n] [-crc32 default|c|k|k2|q] <key>...`
+
+var polynomials = map[string]uint32{
+	"default": str.CRC32TypeDefault,
+	"c":       str.CRC32TypeC,
+	"k":       str.CRC32TypeK,
+	"k2":      str.CRC32TypeK2,
+	"q":       str.CRC32TypeQ,
+}
+
+var (
+	length  uint
+	charset string
+	prefix  string
+	crc     string
+	times   int
+)
+
+func main() {
+	cmd := ""
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+
+	fs := flag.NewFlagSet("token", flag.ExitOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&prefix, "prefix", "", "token prefix")
+	fs.IntVar(&times, "n", 1, "number of tokens to generate")
+
+	switch cmd {
+	case "":
+		fs.UintVar(&length, "length", token.DefaultLength, "token length, including the prefix")
+		fs.StringVar(&charset, "charset", token.DefaultCharset, "characters to draw from")
+		_ = fs.Parse(args)
+		generate(func() (string, error) {
+			return token.WithOptions(&token.Options{Length: length, Charset: charset, Prefix: prefix})
+		})
+	case "apikey", "verify":
+		fs.UintVar(&length, "length", token.DefaultAPIKeyLength, "number of random characters")
+		fs.StringVar(&crc, "crc32", "default", "crc32 polynomial for the checksum (default|c|k|k2|q)")
+		_ = fs.Parse(args)
+
+		poly, ok := polynomials[crc]
+		if !ok {
+			fatal(fmt.Errorf("'%s' is an invalid crc32 polynomial. use default, c, k, k2 or q", crc))
+		}
+		opts := token.APIKeyOptions{Prefix: prefix, Length: length, Polynomial: poly}
+		if cmd == "apikey" {
+			generate(func() (string, error) { return token.APIKey(opts) })
+			return
+		}
+		verify(opts, fs.Args())
+	default:
+		fatal(fmt.Errorf("unknown command '%s'\n%s", cmd, usage))
+	}
+}
+
+func generate(fn func() (string, error)) {
+	for i := 0; i < times; i++ {
+		t, err := fn()
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Println(t)
+	}
+}
+
+// verify checks each key and exits with status 1 if any of them are invalid.
+func verify(opts token.APIKeyOptions, keys []string) {
+	if len(keys) == 0 {
+		fatal(fmt.Errorf(usage))
+	}
+	failed := false
+	for _, k := range keys {
+		if err := token.VerifyAPIKey(k, opts); err != nil {
+			fmt.Printf("%s: FAILED (%v)\n", k, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s: OK\n", k)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func fatal(err error) {
+	_, _ = fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
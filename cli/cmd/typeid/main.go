@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/schigh/tools/pkg/typeid"
+)
+
+var (
+	prefix string
+	fromID string
+	times  int
+)
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "inspect" {
+		inspect(os.Args[2:])
+		return
+	}
+
+	flag.StringVar(&prefix, "prefix", "", "type prefix (lowercase a-z and _, up to 63 characters)")
+	flag.StringVar(&fromID, "uuid", "", "convert an existing uuid instead of generating a new one")
+	flag.IntVar(&times, "n", 1, "number of ids to generate")
+	flag.Parse()
+
+	if fromID != "" {
+		u, err := uuid.Parse(fromID)
+		if err != nil {
+			fatal(err)
+		}
+		tid, err := typeid.FromUUID(prefix, u)
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Println(tid)
+		return
+	}
+
+	for i := 0; i < times; i++ {
+		tid, err := typeid.New(prefix)
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Println(tid)
+	}
+}
+
+// inspect prints the components of each TypeID passed on the command line.
+func inspect(args []string) {
+	if len(args) == 0 {
+		fatal(fmt.Errorf("usage: typeid inspect <typeid>..."))
+	}
+
+	for i, arg := range args {
+		tid, err := typeid.Parse(arg)
+		if err != nil {
+			fatal(err)
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%-8s%s\n", "typeid", tid)
+		fmt.Printf("%-8s%s\n", "prefix", tid.Prefix())
+		fmt.Printf("%-8s%s\n", "suffix", tid.Suffix())
+		fmt.Printf("%-8s%s\n", "uuid", tid.UUID())
+		fmt.Printf("%-8s%d\n", "version", tid.UUID().Version())
+		if t := tid.Time(); !t.IsZero() {
+			fmt.Printf("%-8s%s\n", "time", t.UTC().Format(time.RFC3339Nano))
+		}
+	}
+}
+
+func fatal(err error) {
+	_, _ = fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
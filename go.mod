@@ -7,6 +7,10 @@ require (
 	github.com/ntwrk1/guid v1.2.0
 	github.com/schigh/str v0.0.0-20170715183927-361193c512d2
 	golang.org/x/crypto v0.0.0-20220315160706-3147a52a75dd
+	golang.org/x/text v0.13.0
 )
 
-require github.com/stretchr/testify v1.6.1 // indirect
+require (
+	github.com/stretchr/testify v1.6.1 // indirect
+	golang.org/x/sys v0.7.0 // indirect
+)
@@ -0,0 +1,154 @@
+// Package bcrypthash inspects bcrypt hashes and checks them against a cost
+// policy, on top of golang.org/x/crypto/bcrypt.
+//
+// A bcrypt hash is 60 characters laid out as $<version>$<cost>$<salt><hash>,
+// for example
+//
+//	$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy
+//
+// is version 2a with a cost of 10, the salt N9qo8uLOickgx2ZMRZoMye and the
+// hash IjZAgcfl7p92ldGxad68LJZdL17lhWy. The salt is 22 and the hash 31
+// characters of bcrypt's own base64 alphabet.
+package bcrypthash
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// MaxSecretLength is the number of bytes of a secret bcrypt uses. Any
+	// bytes past it are silently ignored, so two secrets that share their
+	// first 72 bytes have the same hash.
+	MaxSecretLength = 72
+	// Length is the length of an encoded bcrypt hash.
+	Length = 60
+
+	saltLength = 22
+	hashLength = 31
+	alphabet   = "./ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+)
+
+// Info describes a bcrypt hash.
+type Info struct {
+	// Version is the algorithm revision, such as "2a" or "2b".
+	Version string `json:"version"`
+	// Cost is the log2 of the number of key expansion rounds.
+	Cost int `json:"cost"`
+	// Salt is the encoded salt.
+	Salt string `json:"salt"`
+	// Hash is the encoded hash, without the version, cost or salt.
+	Hash string `json:"hash"`
+}
+
+// Inspect parses a bcrypt hash. bcrypt.Cost validates the version and cost;
+// Inspect also checks the length and that the salt and hash are in the
+// bcrypt alphabet, which bcrypt.Cost does not.
+func Inspect(hash string) (Info, error) {
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return Info{}, err
+	}
+	if len(hash) != Length {
+		return Info{}, fmt.Errorf("bcrypt hashes are %d characters. got %d", Length, len(hash))
+	}
+
+	// $2$ has a one character version; the rest have two
+	fields := strings.Split(hash, "$")
+	if len(fields) != 4 || fields[0] != "" {
+		return Info{}, fmt.Errorf("'%s' is not laid out as $version$cost$salthash", hash)
+	}
+	rest := fields[3]
+	if len(rest) != saltLength+hashLength {
+		return Info{}, fmt.Errorf("the salt and hash are %d characters. expected %d", len(rest), saltLength+hashLength)
+	}
+	if i := strings.IndexFunc(rest, func(r rune) bool { return !strings.ContainsRune(alphabet, r) }); i >= 0 {
+		return Info{}, fmt.Errorf("'%c' is not in the bcrypt alphabet", rest[i])
+	}
+
+	return Info{
+		Version: fields[1],
+		Cost:    cost,
+		Salt:    rest[:saltLength],
+		Hash:    rest[saltLength:],
+	}, nil
+}
+
+// NeedsRehash reports whether hash was created with a cost below minCost,
+// and so should be replaced the next time its secret is known, such as at
+// login. It returns an error if the hash cannot be parsed.
+func NeedsRehash(hash string, minCost int) (bool, error) {
+	info, err := Inspect(hash)
+	if err != nil {
+		return false, err
+	}
+	return info.Cost < minCost, nil
+}
+
+// CheckLength returns an error if bcrypt would ignore part of secret.
+func CheckLength(secret []byte) error {
+	if len(secret) > MaxSecretLength {
+		return fmt.Errorf("the secret is %d bytes but bcrypt only uses the first %d. the rest is ignored", len(secret), MaxSecretLength)
+	}
+	return nil
+}
+
+// ParseCost converts a cost given on the command line into a bcrypt cost. It
+// accepts a number from bcrypt.MinCost to bcrypt.MaxCost, or "min", "max" or
+// "default".
+func ParseCost(s string) (int, error) {
+	switch s {
+	case "min":
+		return bcrypt.MinCost, nil
+	case "max":
+		return bcrypt.MaxCost, nil
+	case "default":
+		return bcrypt.DefaultCost, nil
+	}
+	c, err := strconv.Atoi(s)
+	if err != nil || c < bcrypt.MinCost || c > bcrypt.MaxCost {
+		return 0, fmt.Errorf("'%s' is an invalid cost. use a number from %d to %d, 'min', 'max' or omit the flag to use default", s, bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	return c, nil
+}
+
+// Pepper returns the value peppered mode passes to bcrypt in place of the
+// secret:
+//
+//	base64(HMAC-SHA256(key = pepper, message = secret))
+//
+// using standard base64 with padding. The result is always 44 bytes, so a
+// secret of any length keeps all of its entropy instead of being cut off at
+// MaxSecretLength, and it contains no NUL bytes, which some bcrypt
+// implementations stop at. The pepper is a key kept apart from the hashes,
+// such as in a secret manager, so that a leaked database cannot be guessed
+// at without it.
+//
+// The construction is fixed so other services can reproduce it:
+//
+//	pepper "pepper", secret "password"
+//	    mVOblvchLJebWnl1XWut4MPNxkILllQ8GpSfmrhZvqE=
+//	pepper bytes 0x00 to 0x1f, secret 100 times "a"
+//	    C44Sn5m3Mu5xsBknEFzYMZkgWR9DyT6pAP/t1hUE1RE=
+//
+// and with pepper "pepper", secret "password" verifies against
+//
+//	$2a$04$i7C4oQ9.d8Sp8S8QdVSLDuLM4K8gWZ2gOlryH.k6zT9IrxJTXxPpS
+func Pepper(secret, pepper []byte) ([]byte, error) {
+	if len(pepper) == 0 {
+		return nil, fmt.Errorf("the pepper is empty")
+	}
+	mac := hmac.New(sha256.New, pepper)
+	_, _ = mac.Write(secret)
+	sum := mac.Sum(nil)
+
+	out := make([]byte, base64.StdEncoding.EncodedLen(len(sum)))
+	base64.StdEncoding.Encode(out, sum)
+	return out, nil
+}
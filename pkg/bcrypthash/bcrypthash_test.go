@@ -0,0 +1,71 @@
+package bcrypthash
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// pepperedHash is a bcrypt hash of Pepper("password", "pepper").
+const pepperedHash = "$2a$04$i7C4oQ9.d8Sp8S8QdVSLDuLM4K8gWZ2gOlryH.k6zT9IrxJTXxPpS"
+
+func sequence(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	return b
+}
+
+func TestPepperVectors(t *testing.T) {
+	tests := []struct {
+		name   string
+		pepper []byte
+		secret []byte
+		want   string
+	}{
+		{
+			name:   "short",
+			pepper: []byte("pepper"),
+			secret: []byte("password"),
+			want:   "mVOblvchLJebWnl1XWut4MPNxkILllQ8GpSfmrhZvqE=",
+		},
+		{
+			name:   "longer than bcrypt's limit",
+			pepper: sequence(32),
+			secret: bytes.Repeat([]byte("a"), 100),
+			want:   "C44Sn5m3Mu5xsBknEFzYMZkgWR9DyT6pAP/t1hUE1RE=",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Pepper(tt.secret, tt.pepper)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Pepper = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPepperedHashVerifies(t *testing.T) {
+	peppered, err := Pepper([]byte("password"), []byte("pepper"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(pepperedHash), peppered); err != nil {
+		t.Errorf("the peppered secret does not verify: %v", err)
+	}
+
+	wrong, err := Pepper([]byte("password"), []byte("paprika"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(pepperedHash), wrong); !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		t.Errorf("a wrong pepper gave %v, want %v", err, bcrypt.ErrMismatchedHashAndPassword)
+	}
+}
@@ -0,0 +1,354 @@
+// Package codes generates and verifies human-friendly codes, such as gift
+// vouchers and license keys, from a small template language.
+//
+// A template is a sequence of character classes and literals:
+//
+//	9      a digit, 0-9
+//	A      an uppercase letter, A-Z
+//	a      a lowercase letter, a-z
+//	X      an uppercase letter or digit
+//	x      a lowercase letter or digit
+//	C      a Crockford base32 character (no I, L, O or U)
+//	[...]  any one of the listed characters; ranges such as A-F are allowed
+//	{n}    repeat the previous class or literal n times
+//	\c     the literal character c
+//
+// Every other character is a literal; class letters must be escaped to be
+// used literally, as in LI\C-X{8}. For example XXXX-XXXX-9999 and
+// X{4}-X{4}-9{4} describe the same codes. Random characters are drawn with
+// package randstr, so every character in a class is equally likely.
+//
+// A template may append a check character computed over the random
+// characters (literals are ignored). See Checksum for the available
+// algorithms.
+package codes
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/schigh/tools/pkg/randstr"
+)
+
+// Character classes.
+const (
+	digits   = "0123456789"
+	upper    = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	lower    = "abcdefghijklmnopqrstuvwxyz"
+	maxCount = 256
+)
+
+var classes = map[rune]string{ //nolint:gochecknoglobals
+	'9': digits,
+	'A': upper,
+	'a': lower,
+	'X': digits + upper,
+	'x': digits + lower,
+	'C': randstr.Crockford32,
+}
+
+// token is one element of a compiled template: either count random
+// characters from alphabet, or count copies of literal.
+type token struct {
+	alphabet string
+	literal  rune
+	count    int
+}
+
+// Template is a compiled code template. It is safe for concurrent use.
+type Template struct {
+	pattern  string
+	tokens   []token
+	checksum Checksum
+	// checkAlphabet is the sorted union of every class in the template. The
+	// check character is drawn from it and Luhn mod N uses its size as N.
+	checkAlphabet string
+}
+
+// Compile parses pattern and prepares it for generation and verification with
+// the given checksum.
+func Compile(pattern string, checksum Checksum) (*Template, error) {
+	tokens, err := parse(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Template{
+		pattern:       pattern,
+		tokens:        tokens,
+		checksum:      checksum,
+		checkAlphabet: union(tokens),
+	}
+	if t.checkAlphabet == "" {
+		return nil, fmt.Errorf("template '%s' has no random characters", pattern)
+	}
+	if err := checksum.supports(t.checkAlphabet); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// MustCompile is like Compile but panics if the template is invalid.
+func MustCompile(pattern string, checksum Checksum) *Template {
+	t, err := Compile(pattern, checksum)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// String returns the template's pattern.
+func (t *Template) String() string {
+	return t.pattern
+}
+
+// Space returns the number of distinct codes the template can produce. The
+// check character does not add to it.
+func (t *Template) Space() float64 {
+	space := 1.0
+	for _, tok := range t.tokens {
+		if tok.alphabet != "" {
+			space *= math.Pow(float64(utf8.RuneCountInString(tok.alphabet)), float64(tok.count))
+		}
+	}
+	return space
+}
+
+// Generate creates a single random code.
+func (t *Template) Generate() (string, error) {
+	sb := strings.Builder{}
+	for _, tok := range t.tokens {
+		if tok.alphabet == "" {
+			sb.WriteString(strings.Repeat(string(tok.literal), tok.count))
+			continue
+		}
+		s, err := randstr.String(tok.alphabet, tok.count)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(s)
+	}
+
+	code := sb.String()
+	if t.checksum == None {
+		return code, nil
+	}
+	c, err := t.checksum.compute(t.random(code), t.checkAlphabet)
+	if err != nil {
+		return "", err
+	}
+
+	return code + string(c), nil
+}
+
+// GenerateBatch creates n codes, none of which repeat within the batch. It
+// fails rather than loop forever when n is close to or larger than the
+// template's Space.
+func (t *Template) GenerateBatch(n int) ([]string, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("'%d' is an invalid batch size. it must be at least 1", n)
+	}
+	// Past half of the space, duplicates become so frequent that a batch is
+	// a sign of a template that is too small rather than bad luck.
+	if float64(n) > t.Space()/2 {
+		return nil, fmt.Errorf("template '%s' can produce %.0f codes, which is too few for a batch of %d", t.pattern, t.Space(), n)
+	}
+
+	seen := make(map[string]struct{}, n)
+	out := make([]string, 0, n)
+	for len(out) < n {
+		code, err := t.Generate()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := seen[code]; ok {
+			continue
+		}
+		seen[code] = struct{}{}
+		out = append(out, code)
+	}
+
+	return out, nil
+}
+
+// Verify returns an error if code does not match the template or its check
+// character is wrong.
+func (t *Template) Verify(code string) error {
+	runes := []rune(code)
+	if t.checksum != None {
+		if len(runes) == 0 {
+			return fmt.Errorf("code is empty")
+		}
+		runes = runes[:len(runes)-1]
+	}
+
+	pos := 0
+	for _, tok := range t.tokens {
+		for i := 0; i < tok.count; i++ {
+			if pos >= len(runes) {
+				return fmt.Errorf("code '%s' is too short for template '%s'", code, t.pattern)
+			}
+			r := runes[pos]
+			if tok.alphabet == "" && r != tok.literal {
+				return fmt.Errorf("code '%s' has '%c' at position %d. expected '%c'", code, r, pos+1, tok.literal)
+			}
+			if tok.alphabet != "" && !strings.ContainsRune(tok.alphabet, r) {
+				return fmt.Errorf("code '%s' has '%c' at position %d, which is not allowed by template '%s'", code, r, pos+1, t.pattern)
+			}
+			pos++
+		}
+	}
+	if pos != len(runes) {
+		return fmt.Errorf("code '%s' is too long for template '%s'", code, t.pattern)
+	}
+
+	if t.checksum == None {
+		return nil
+	}
+	want, err := t.checksum.compute(t.random(string(runes)), t.checkAlphabet)
+	if err != nil {
+		return err
+	}
+	if got := []rune(code)[len(runes)]; got != want {
+		return fmt.Errorf("code '%s' failed the %s check", code, t.checksum)
+	}
+
+	return nil
+}
+
+// random returns the characters of code produced by character classes,
+// skipping literals. code must already match the template.
+func (t *Template) random(code string) string {
+	runes := []rune(code)
+	sb := strings.Builder{}
+	pos := 0
+	for _, tok := range t.tokens {
+		if tok.alphabet != "" {
+			sb.WriteString(string(runes[pos : pos+tok.count]))
+		}
+		pos += tok.count
+	}
+	return sb.String()
+}
+
+// parse converts a pattern into tokens.
+func parse(pattern string) ([]token, error) {
+	runes := []rune(pattern)
+	var tokens []token
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\':
+			if i+1 >= len(runes) {
+				return nil, fmt.Errorf("template '%s' ends with an unfinished escape", pattern)
+			}
+			i++
+			tokens = append(tokens, token{literal: runes[i], count: 1})
+		case r == '[':
+			end := i + 1
+			for end < len(runes) && runes[end] != ']' {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("template '%s' has an unterminated '['", pattern)
+			}
+			alphabet, err := expand(runes[i+1 : end])
+			if err != nil {
+				return nil, fmt.Errorf("template '%s': %w", pattern, err)
+			}
+			tokens = append(tokens, token{alphabet: alphabet, count: 1})
+			i = end
+		case r == '{':
+			end := i + 1
+			for end < len(runes) && runes[end] != '}' {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("template '%s' has an unterminated '{'", pattern)
+			}
+			if len(tokens) == 0 {
+				return nil, fmt.Errorf("template '%s' has a repeat with nothing to repeat", pattern)
+			}
+			n, err := strconv.Atoi(string(runes[i+1 : end]))
+			if err != nil || n < 1 || n > maxCount {
+				return nil, fmt.Errorf("template '%s' has an invalid repeat '%s'. it must be between 1 and %d", pattern, string(runes[i:end+1]), maxCount)
+			}
+			// the previous token already accounts for one occurrence
+			tokens[len(tokens)-1].count += n - 1
+			i = end
+		default:
+			if alphabet, ok := classes[r]; ok {
+				tokens = append(tokens, token{alphabet: alphabet, count: 1})
+				continue
+			}
+			tokens = append(tokens, token{literal: r, count: 1})
+		}
+	}
+
+	return merge(tokens), nil
+}
+
+// merge combines adjacent tokens with the same class or literal so that
+// XXXX becomes a single token with a count of 4.
+func merge(tokens []token) []token {
+	var out []token
+	for _, tok := range tokens {
+		if n := len(out); n > 0 && out[n-1].alphabet == tok.alphabet && out[n-1].literal == tok.literal {
+			out[n-1].count += tok.count
+			continue
+		}
+		out = append(out, tok)
+	}
+	return out
+}
+
+// expand converts the contents of a [...] class into its characters.
+func expand(set []rune) (string, error) {
+	if len(set) == 0 {
+		return "", fmt.Errorf("character class '[]' is empty")
+	}
+	seen := map[rune]struct{}{}
+	for i := 0; i < len(set); i++ {
+		lo, hi := set[i], set[i]
+		if i+2 < len(set) && set[i+1] == '-' {
+			hi = set[i+2]
+			i += 2
+		}
+		if hi < lo {
+			return "", fmt.Errorf("character range '%c-%c' is reversed", lo, hi)
+		}
+		for r := lo; r <= hi; r++ {
+			seen[r] = struct{}{}
+		}
+	}
+	if len(seen) < 2 {
+		return "", fmt.Errorf("character class '[%s]' must contain at least 2 characters", string(set))
+	}
+	return sortedString(seen), nil
+}
+
+// union returns the sorted set of every character any class in tokens can
+// produce.
+func union(tokens []token) string {
+	seen := map[rune]struct{}{}
+	for _, tok := range tokens {
+		for _, r := range tok.alphabet {
+			seen[r] = struct{}{}
+		}
+	}
+	return sortedString(seen)
+}
+
+func sortedString(set map[rune]struct{}) string {
+	runes := make([]rune, 0, len(set))
+	for r := range set {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	return string(runes)
+}
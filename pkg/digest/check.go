@@ -0,0 +1,161 @@
+package digest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Entry is one parsed line of a checksum file.
+type Entry struct {
+	// Line is the 1-based line number the entry was read from.
+	Line int
+	// Tag is the algorithm named by a BSD-style line, such as "SHA256". It
+	// is empty for GNU-style lines, which do not name their algorithm.
+	Tag string
+	// Digest is the expected digest as written, in any encoding Decode
+	// accepts. It is not decoded until the algorithm, and so the digest
+	// size, is known.
+	Digest string
+	// Name is the file the digest belongs to, with escapes removed.
+	Name string
+	// Binary reports whether a GNU-style line used the '*' binary mode
+	// marker. It has no effect on the digest; both modes read the file
+	// byte for byte.
+	Binary bool
+}
+
+// ParseError describes a line of a checksum file that could not be parsed.
+type ParseError struct {
+	Line int
+	Text string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: improperly formatted checksum line", e.Line)
+}
+
+// ParseSums reads a checksum file in any of the formats written by GNU
+// coreutils or BSD tools:
+//
+//	<digest>  <name>             GNU text mode
+//	<digest> *<name>             GNU binary mode
+//	<TAG> (<name>) = <digest>    BSD tag format (also `sha256sum --tag`)
+//
+// Digests are usually hex, but any encoding that Decode accepts is allowed.
+// A leading backslash marks a line whose name contains escaped backslashes,
+// newlines or carriage returns. Blank lines and lines starting with '#' are
+// skipped. Lines that do not match any format are returned as ParseErrors
+// rather than stopping the parse, so that callers can report them the way
+// coreutils does and still verify the rest.
+func ParseSums(r io.Reader) ([]Entry, []*ParseError, error) {
+	var (
+		entries []Entry
+		bad     []*ParseError
+	)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		e, ok := ParseLine(text)
+		if !ok {
+			bad = append(bad, &ParseError{Line: line, Text: text})
+			continue
+		}
+		e.Line = line
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return entries, bad, nil
+}
+
+// ParseLine parses a single checksum line. See ParseSums for the accepted
+// formats.
+func ParseLine(text string) (Entry, bool) {
+	escaped := strings.HasPrefix(text, `\`)
+	if escaped {
+		text = text[1:]
+	}
+
+	e, ok := parseTagLine(text)
+	if !ok {
+		e, ok = parseGNULine(text)
+	}
+	if !ok {
+		return Entry{}, false
+	}
+	if escaped {
+		e.Name = Unescape(e.Name)
+	}
+	return e, true
+}
+
+// parseGNULine parses "<digest>  <name>" and "<digest> *<name>".
+func parseGNULine(text string) (Entry, bool) {
+	idx := strings.IndexByte(text, ' ')
+	if idx <= 0 || idx+2 > len(text) || !isDigest(text[:idx]) {
+		return Entry{}, false
+	}
+
+	e := Entry{Digest: text[:idx]}
+	switch text[idx+1] {
+	case ' ':
+	case '*':
+		e.Binary = true
+	default:
+		return Entry{}, false
+	}
+	e.Name = text[idx+2:]
+	if e.Name == "" {
+		return Entry{}, false
+	}
+	return e, true
+}
+
+// parseTagLine parses "<TAG> (<name>) = <digest>". The name is everything
+// between the first " (" and the last ") = ", so names containing
+// parentheses survive.
+func parseTagLine(text string) (Entry, bool) {
+	open := strings.Index(text, " (")
+	closing := strings.LastIndex(text, ") = ")
+	if open <= 0 || closing < open+2 {
+		return Entry{}, false
+	}
+	tag := text[:open]
+	if strings.ContainsAny(tag, " \t") {
+		return Entry{}, false
+	}
+	sum := text[closing+4:]
+	name := text[open+2 : closing]
+	if name == "" || !isDigest(sum) {
+		return Entry{}, false
+	}
+
+	return Entry{Tag: tag, Digest: sum, Name: name}, true
+}
+
+// isDigest reports whether s only contains characters used by the hex,
+// base32, base64 and SRI encodings.
+func isDigest(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r == '+', r == '/', r == '=', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,128 @@
+package digest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const helloSHA256 = "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want Entry
+		ok   bool
+	}{
+		{
+			name: "gnu text",
+			line: helloSHA256 + "  hello.txt",
+			want: Entry{Digest: helloSHA256, Name: "hello.txt"},
+			ok:   true,
+		},
+		{
+			name: "gnu binary",
+			line: helloSHA256 + " *hello.txt",
+			want: Entry{Digest: helloSHA256, Name: "hello.txt", Binary: true},
+			ok:   true,
+		},
+		{
+			name: "gnu name with spaces",
+			line: helloSHA256 + "  my file.txt",
+			want: Entry{Digest: helloSHA256, Name: "my file.txt"},
+			ok:   true,
+		},
+		{
+			name: "bsd",
+			line: "SHA256 (hello.txt) = " + helloSHA256,
+			want: Entry{Tag: "SHA256", Digest: helloSHA256, Name: "hello.txt"},
+			ok:   true,
+		},
+		{
+			name: "bsd name with parentheses",
+			line: "SHA256 (a (1)) = " + helloSHA256,
+			want: Entry{Tag: "SHA256", Digest: helloSHA256, Name: "a (1)"},
+			ok:   true,
+		},
+		{
+			name: "escaped gnu",
+			line: `\` + helloSHA256 + `  a\nb\\c`,
+			want: Entry{Digest: helloSHA256, Name: "a\nb\\c"},
+			ok:   true,
+		},
+		{
+			name: "escaped bsd",
+			line: `\SHA256 (a\rb) = ` + helloSHA256,
+			want: Entry{Tag: "SHA256", Digest: helloSHA256, Name: "a\rb"},
+			ok:   true,
+		},
+		{
+			name: "unescaped backslash kept",
+			line: helloSHA256 + `  a\nb`,
+			want: Entry{Digest: helloSHA256, Name: `a\nb`},
+			ok:   true,
+		},
+		{name: "single space", line: helloSHA256 + " hello.txt"},
+		{name: "no name", line: helloSHA256 + "  "},
+		{name: "digest only", line: helloSHA256},
+		{name: "not a digest", line: "not!hex  hello.txt"},
+		{name: "bsd without digest", line: "SHA256 (hello.txt) = "},
+		{name: "bsd without name", line: "SHA256 () = " + helloSHA256},
+		{name: "bsd tag with space", line: "SHA 256 (hello.txt) = " + helloSHA256},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseLine(tt.line)
+			if ok != tt.ok {
+				t.Fatalf("ParseLine(%q) ok = %v, want %v", tt.line, ok, tt.ok)
+			}
+			if ok && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLine(%q) = %+v, want %+v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSums(t *testing.T) {
+	input := strings.Join([]string{
+		"# a comment",
+		helloSHA256 + "  a.txt",
+		"",
+		"   ",
+		"garbage",
+		"SHA256 (b.txt) = " + helloSHA256 + "\r",
+		helloSHA256 + " *c.txt",
+		"SHA256 (d.txt) =",
+	}, "\n")
+
+	entries, malformed, err := ParseSums(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	var lines []int
+	for _, e := range entries {
+		names = append(names, e.Name)
+		lines = append(lines, e.Line)
+	}
+	if want := []string{"a.txt", "b.txt", "c.txt"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %q, want %q", names, want)
+	}
+	if want := []int{2, 6, 7}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("lines = %v, want %v", lines, want)
+	}
+
+	if len(malformed) != 2 {
+		t.Fatalf("%d malformed lines, want 2", len(malformed))
+	}
+	for i, want := range []struct {
+		line int
+		text string
+	}{{5, "garbage"}, {8, "SHA256 (d.txt) ="}} {
+		if malformed[i].Line != want.line || malformed[i].Text != want.text {
+			t.Errorf("malformed[%d] = line %d %q, want line %d %q", i, malformed[i].Line, malformed[i].Text, want.line, want.text)
+		}
+	}
+}
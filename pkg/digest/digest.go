@@ -0,0 +1,135 @@
+// Package digest hashes files and streams in constant memory and formats the
+// results the way GNU coreutils' md5sum, sha1sum and sha256sum do.
+package digest
+
+import (
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Stdin is the file name that refers to standard input.
+const Stdin = "-"
+
+// Reader hashes everything read from r with a new hash from newHash and
+// returns the sum. The data is streamed, so memory use does not depend on the
+// size of the input.
+func Reader(newHash func() hash.Hash, r io.Reader) ([]byte, error) {
+	h := newHash()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// Multi hashes everything read from r with one hash per constructor in a
+// single pass, using io.MultiWriter, and returns the sums in the same order.
+func Multi(newHashes []func() hash.Hash, r io.Reader) ([][]byte, error) {
+	hashes := make([]hash.Hash, len(newHashes))
+	writers := make([]io.Writer, len(newHashes))
+	for i, fn := range newHashes {
+		hashes[i] = fn()
+		writers[i] = hashes[i]
+	}
+	if _, err := io.Copy(io.MultiWriter(writers...), r); err != nil {
+		return nil, err
+	}
+
+	sums := make([][]byte, len(hashes))
+	for i, h := range hashes {
+		sums[i] = h.Sum(nil)
+	}
+	return sums, nil
+}
+
+// File hashes the file at path. Stdin reads from standard input.
+func File(newHash func() hash.Hash, path string) ([]byte, error) {
+	sums, err := MultiFile([]func() hash.Hash{newHash}, path)
+	if err != nil {
+		return nil, err
+	}
+	return sums[0], nil
+}
+
+// MultiFile hashes the file at path with every constructor in a single pass.
+// Stdin reads from standard input.
+func MultiFile(newHashes []func() hash.Hash, path string) ([][]byte, error) {
+	if path == Stdin {
+		return Multi(newHashes, os.Stdin)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Multi(newHashes, f)
+}
+
+// Expand resolves glob patterns into file names. Patterns without glob
+// metacharacters, patterns that match nothing and Stdin are passed through
+// unchanged, so that a missing file is reported when it is opened rather
+// than silently dropped. Matches for each pattern are sorted.
+func Expand(patterns []string) ([]string, error) {
+	out := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		if p == Stdin || !strings.ContainsAny(p, `*?[`) {
+			out = append(out, p)
+			continue
+		}
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			out = append(out, p)
+			continue
+		}
+		sort.Strings(matches)
+		out = append(out, matches...)
+	}
+	return out, nil
+}
+
+// Line formats an encoded digest and file name as a coreutils checksum line:
+// "<digest>  <name>". As in coreutils, a name containing a backslash,
+// newline or carriage return is escaped and the line is prefixed with a
+// backslash so that it can be parsed back unambiguously.
+func Line(sum string, name string) string {
+	escaped, ok := Escape(name)
+	if ok {
+		return `\` + sum + "  " + escaped
+	}
+	return sum + "  " + name
+}
+
+// TagLine formats an encoded digest and file name as a BSD-style tagged
+// checksum line: "<TAG> (<name>) = <digest>". Names are escaped as in Line.
+func TagLine(tag string, sum string, name string) string {
+	escaped, ok := Escape(name)
+	line := tag + " (" + escaped + ") = " + sum
+	if ok {
+		return `\` + line
+	}
+	return line
+}
+
+// Escape escapes backslashes, newlines and carriage returns in name. It
+// reports whether any escaping was needed.
+func Escape(name string) (string, bool) {
+	if !strings.ContainsAny(name, "\\\n\r") {
+		return name, false
+	}
+	r := strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`)
+	return r.Replace(name), true
+}
+
+// Unescape reverses Escape.
+func Unescape(name string) string {
+	r := strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\r`, "\r")
+	return r.Replace(name)
+}
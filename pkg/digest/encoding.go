@@ -0,0 +1,143 @@
+package digest
+
+import (
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Encoding selects how a digest is printed.
+type Encoding string
+
+// Encodings.
+const (
+	// Hex is lowercase hexadecimal, as printed by coreutils.
+	Hex Encoding = "hex"
+	// HexUpper is uppercase hexadecimal.
+	HexUpper Encoding = "HEX"
+	// Base64 is standard, padded base64.
+	Base64 Encoding = "base64"
+	// Base64URL is unpadded base64 with the URL and filename safe alphabet.
+	Base64URL Encoding = "base64url"
+	// Base32 is standard, padded base32.
+	Base32 Encoding = "base32"
+	// SRI is a Subresource Integrity string: the algorithm name, a hyphen
+	// and the standard base64 digest, such as "sha384-oqVuAfXR...". Only
+	// sha256, sha384 and sha512 are allowed by the specification.
+	SRI Encoding = "sri"
+	// Raw is the digest bytes themselves.
+	Raw Encoding = "raw"
+)
+
+// sriAlgorithms are the algorithms the Subresource Integrity specification
+// allows, by the names it uses.
+var sriAlgorithms = map[string]bool{ //nolint:gochecknoglobals
+	"sha256": true,
+	"sha384": true,
+	"sha512": true,
+}
+
+// Encodings returns the name of every encoding.
+func Encodings() []string {
+	return []string{string(Hex), string(HexUpper), string(Base64), string(Base64URL), string(Base32), string(SRI), string(Raw)}
+}
+
+// ParseEncoding converts an encoding name into an Encoding. Names are
+// case-insensitive except for hex and HEX.
+func ParseEncoding(s string) (Encoding, error) {
+	if s == string(Hex) || s == string(HexUpper) {
+		return Encoding(s), nil
+	}
+	for _, e := range Encodings() {
+		if strings.EqualFold(s, e) && e != string(Hex) {
+			return Encoding(e), nil
+		}
+	}
+	return "", fmt.Errorf("'%s' is an invalid encoding. use one of: %s", s, strings.Join(Encodings(), ", "))
+}
+
+// SupportsSRI reports whether algorithm may be used in a Subresource
+// Integrity string.
+func SupportsSRI(algorithm string) bool {
+	return sriAlgorithms[algorithm]
+}
+
+// Encode formats sum with e. algorithm is the name of the hash that produced
+// sum, which is only used by SRI.
+func Encode(e Encoding, algorithm string, sum []byte) (string, error) {
+	switch e {
+	case Hex:
+		return hex.EncodeToString(sum), nil
+	case HexUpper:
+		return strings.ToUpper(hex.EncodeToString(sum)), nil
+	case Base64:
+		return base64.StdEncoding.EncodeToString(sum), nil
+	case Base64URL:
+		return base64.RawURLEncoding.EncodeToString(sum), nil
+	case Base32:
+		return base32.StdEncoding.EncodeToString(sum), nil
+	case SRI:
+		if !SupportsSRI(algorithm) {
+			return "", fmt.Errorf("%s cannot be used for subresource integrity. use sha256, sha384 or sha512", algorithm)
+		}
+		return algorithm + "-" + base64.StdEncoding.EncodeToString(sum), nil
+	case Raw:
+		return string(sum), nil
+	default:
+		return "", fmt.Errorf("'%s' is an invalid encoding", string(e))
+	}
+}
+
+// Decode parses a digest of size bytes printed in any encoding but Raw. The
+// encoding is recognised from the text: hex when it is 2*size hex digits,
+// otherwise base64 (standard or URL safe, padded or not) or base32. An SRI
+// string is accepted if its algorithm prefix matches algorithm.
+func Decode(s string, algorithm string, size int) ([]byte, error) {
+	if alg, rest, ok := splitSRI(s); ok {
+		if alg != algorithm {
+			return nil, fmt.Errorf("'%s' is a %s digest, not %s", s, alg, algorithm)
+		}
+		s = rest
+	}
+
+	if len(s) == hex.EncodedLen(size) {
+		if sum, err := hex.DecodeString(s); err == nil {
+			return sum, nil
+		}
+	}
+	decoders := []func(string) ([]byte, error){
+		base64.StdEncoding.DecodeString,
+		base64.RawStdEncoding.DecodeString,
+		base64.URLEncoding.DecodeString,
+		base64.RawURLEncoding.DecodeString,
+		base32.StdEncoding.DecodeString,
+		base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString,
+	}
+	for _, decode := range decoders {
+		if sum, err := decode(s); err == nil && len(sum) == size {
+			return sum, nil
+		}
+	}
+	return nil, fmt.Errorf("'%s' is not a %d byte %s digest", s, size, algorithm)
+}
+
+// splitSRI splits an SRI string into its algorithm and base64 digest. A
+// base64url digest may contain hyphens too, so only the algorithms SRI
+// allows are recognised as prefixes.
+func splitSRI(s string) (string, string, bool) {
+	idx := strings.IndexByte(s, '-')
+	if idx < 0 || !sriAlgorithms[s[:idx]] {
+		return "", "", false
+	}
+	return s[:idx], s[idx+1:], true
+}
+
+// Equal reports whether two digests are the same. It takes the same time
+// whichever byte differs, so comparing a secret digest, such as a MAC, does
+// not reveal how much of it an attacker has guessed.
+func Equal(a, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
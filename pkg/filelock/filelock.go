@@ -0,0 +1,20 @@
+// Package filelock serializes writers of a file across processes.
+//
+// The lock is never taken on the protected file itself, so that the file can
+// be replaced atomically by renaming a new one over it without the lock
+// following the old inode. Lock uses a separate lock file next to it, which
+// is left in place; LockDir locks the directory holding it, which leaves
+// nothing behind but also serializes writers of every file in the directory.
+package filelock
+
+// Lock takes an exclusive lock on path, creating it if needed, and returns a
+// function that releases it. It blocks until the lock is available.
+func Lock(path string) (func(), error) {
+	return lock(path)
+}
+
+// LockDir takes an exclusive lock on the directory dir and returns a function
+// that releases it. It blocks until the lock is available.
+func LockDir(dir string) (func(), error) {
+	return lockDir(dir)
+}
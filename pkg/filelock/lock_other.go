@@ -0,0 +1,16 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package filelock
+
+// lock is a no-op where flock is unavailable. Callers that replace files
+// atomically still never leave a partial file, but concurrent writers may
+// lose each other's changes.
+func lock(string) (func(), error) {
+	return func() {}, nil
+}
+
+// lockDir is a no-op for the same reason as lock.
+func lockDir(string) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,189 @@
+// Package guidprefix maps github.com/ntwrk1/guid prefix bytes to the names of
+// the entities they identify, so that "us" can be written and checked as
+// "user".
+//
+// A registry file has one mapping per line: two prefix bytes followed by an
+// entity name, separated by whitespace. Blank lines and lines starting with
+// '#' are ignored.
+//
+//	# prefix entity
+//	us       user
+//	or       order
+package guidprefix
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ntwrk1/guid"
+)
+
+// Prefix is the pair of bytes at the start of every GUID.
+type Prefix [2]byte
+
+// ParsePrefix validates that s is a usable GUID prefix. The rules match the
+// ones guid.SetGlobalPrefixBytes enforces: exactly two bytes, each a
+// lowercase base36 character.
+func ParsePrefix(s string) (Prefix, error) {
+	if len(s) != 2 {
+		return Prefix{}, fmt.Errorf("'%s' is an invalid prefix. it must be exactly two bytes", s)
+	}
+	for i := 0; i < len(s); i++ {
+		if !isValidPrefixByte(s[i]) {
+			return Prefix{}, fmt.Errorf("'%s' is an invalid prefix. prefix bytes must be lowercase base36 (0-9, a-z)", s)
+		}
+	}
+	return Prefix{s[0], s[1]}, nil
+}
+
+// Of returns the prefix of g.
+func Of(g guid.GUID) Prefix {
+	b1, b2 := g.PrefixBytes()
+	return Prefix{b1, b2}
+}
+
+// Option returns a guid.Option that applies the prefix.
+func (p Prefix) Option() guid.Option {
+	return guid.WithPrefixBytes(p[0], p[1])
+}
+
+func (p Prefix) String() string {
+	return string(p[:])
+}
+
+// Registry is a two-way mapping between prefixes and entity names. The zero
+// value is not usable; create one with New, Read or Load.
+type Registry struct {
+	entities map[Prefix]string
+	prefixes map[string]Prefix
+}
+
+// New creates an empty Registry.
+func New() *Registry {
+	return &Registry{
+		entities: map[Prefix]string{},
+		prefixes: map[string]Prefix{},
+	}
+}
+
+// Load reads a registry file from disk.
+func Load(path string) (*Registry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Read parses a registry from r.
+func Read(r io.Reader) (*Registry, error) {
+	reg := New()
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected '<prefix> <entity>'. got '%s'", line, text)
+		}
+		if err := reg.Register(fields[0], fields[1]); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return reg, nil
+}
+
+// Register adds a mapping. Both the prefix and the entity name must be unique
+// within the registry.
+func (r *Registry) Register(prefix, entity string) error {
+	p, err := ParsePrefix(prefix)
+	if err != nil {
+		return err
+	}
+	if entity == "" {
+		return fmt.Errorf("entity name for prefix '%s' is empty", prefix)
+	}
+	if existing, ok := r.entities[p]; ok {
+		return fmt.Errorf("prefix '%s' is already registered to '%s'", prefix, existing)
+	}
+	if existing, ok := r.prefixes[entity]; ok {
+		return fmt.Errorf("entity '%s' is already registered with prefix '%s'", entity, existing)
+	}
+	r.entities[p] = entity
+	r.prefixes[entity] = p
+
+	return nil
+}
+
+// Prefix returns the prefix registered for entity.
+func (r *Registry) Prefix(entity string) (Prefix, error) {
+	p, ok := r.prefixes[entity]
+	if !ok {
+		return Prefix{}, fmt.Errorf("entity '%s' is not registered", entity)
+	}
+	return p, nil
+}
+
+// Entity returns the entity name registered for the prefix of g.
+func (r *Registry) Entity(g guid.GUID) (string, bool) {
+	e, ok := r.entities[Of(g)]
+	return e, ok
+}
+
+// Entities returns the registered entity names in sorted order.
+func (r *Registry) Entities() []string {
+	out := make([]string, 0, len(r.prefixes))
+	for e := range r.prefixes {
+		out = append(out, e)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// New generates a GUID carrying the prefix registered for entity.
+func (r *Registry) New(entity string) (guid.GUID, error) {
+	p, err := r.Prefix(entity)
+	if err != nil {
+		return guid.GUID{}, err
+	}
+	return guid.NewRandom(p.Option())
+}
+
+// Validate returns an error unless g carries the prefix registered for
+// entity.
+func (r *Registry) Validate(g guid.GUID, entity string) error {
+	p, err := r.Prefix(entity)
+	if err != nil {
+		return err
+	}
+	if actual := Of(g); actual != p {
+		if name, ok := r.entities[actual]; ok {
+			return fmt.Errorf("guid %s belongs to '%s', not '%s'", g, name, entity)
+		}
+		return fmt.Errorf("guid %s has prefix '%s'. expected '%s' for '%s'", g, actual, p, entity)
+	}
+	return nil
+}
+
+// prefix bytes must be printable base36 ASCII chars
+func isValidPrefixByte(b byte) bool {
+	return (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z')
+}
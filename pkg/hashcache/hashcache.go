@@ -0,0 +1,284 @@
+// Package hashcache remembers file digests between runs so that files which
+// have not changed are not read again.
+//
+// An entry is keyed by algorithm and absolute path, and is only used while
+// the file's size, modification time and inode are the same as when it was
+// hashed. Changing any of them, including replacing the file with a new one
+// of the same size and mtime, makes the entry stale. The cache trusts these
+// fields, so it can be fooled by a file whose contents were changed with its
+// mtime reset; use Verify to check.
+//
+// The database is a JSON file under the user cache directory. Readers and
+// writers take a lock on a file next to it, and Save merges with whatever
+// other processes wrote in the meantime, so concurrent runs do not lose each
+// other's entries.
+package hashcache
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/schigh/tools/pkg/digest"
+	"github.com/schigh/tools/pkg/filelock"
+)
+
+const (
+	// dirName is the directory created under the user cache directory.
+	dirName = "schigh-tools"
+	// fileName is the database file name.
+	fileName = "hashcache.json"
+	// version is written to the database so that a future format change
+	// can discard old files instead of misreading them.
+	version = 1
+)
+
+// Entry is a cached digest and the file attributes it is valid for.
+type Entry struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime"`
+	Inode   uint64 `json:"inode"`
+	Sum     string `json:"sum"`
+}
+
+type database struct {
+	Version int              `json:"version"`
+	Entries map[string]Entry `json:"entries"`
+}
+
+// Cache is an open hash cache. It is safe for concurrent use. A nil *Cache
+// is valid and caches nothing, so callers do not need to check whether
+// caching is enabled.
+type Cache struct {
+	// Verify makes File and MultiFile hash every file even when a valid
+	// entry exists, and record the files whose cached digest was wrong.
+	Verify bool
+
+	path       string
+	mu         sync.Mutex
+	entries    map[string]Entry
+	updated    map[string]Entry
+	mismatched []string
+}
+
+// DefaultPath returns the database path under os.UserCacheDir.
+func DefaultPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, dirName, fileName), nil
+}
+
+// Open loads the database at path, creating its directory if needed. A
+// missing or unreadable database is treated as empty rather than an error,
+// since the cache can always be rebuilt.
+func Open(path string) (*Cache, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	c := &Cache{path: path, updated: map[string]Entry{}}
+
+	unlock, err := filelock.Lock(path + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	c.entries = load(path)
+	return c, nil
+}
+
+// load reads the database, returning an empty one if it is missing, corrupt
+// or written by another version.
+func load(path string) map[string]Entry {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return map[string]Entry{}
+	}
+	var db database
+	if err := json.Unmarshal(data, &db); err != nil || db.Version != version || db.Entries == nil {
+		return map[string]Entry{}
+	}
+	return db.Entries
+}
+
+// Save writes the entries added since Open. It re-reads the database under
+// the lock and merges into it, then replaces the file atomically, so a crash
+// never leaves a truncated database.
+func (c *Cache) Save() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.updated) == 0 {
+		return nil
+	}
+
+	unlock, err := filelock.Lock(c.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries := load(c.path)
+	for k, e := range c.updated {
+		entries[k] = e
+	}
+	data, err := json.Marshal(database{Version: version, Entries: entries})
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(c.path), fileName+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return err
+	}
+
+	c.entries = entries
+	c.updated = map[string]Entry{}
+	return nil
+}
+
+// Mismatched returns the files whose cached digest did not match their
+// contents when Verify was set.
+func (c *Cache) Mismatched() []string {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]string, len(c.mismatched))
+	copy(out, c.mismatched)
+	return out
+}
+
+// File is digest.File with caching. algorithm names the hash newHash
+// returns and is part of the key.
+func (c *Cache) File(algorithm string, newHash func() hash.Hash, path string) ([]byte, error) {
+	sums, err := c.MultiFile([]string{algorithm}, []func() hash.Hash{newHash}, path)
+	if err != nil {
+		return nil, err
+	}
+	return sums[0], nil
+}
+
+// MultiFile is digest.MultiFile with caching. algorithms names the hash
+// each of newHashes returns. If every algorithm has a valid entry the file is
+// not read; otherwise it is hashed with all of them and the entries are
+// replaced. Standard input is never cached.
+func (c *Cache) MultiFile(algorithms []string, newHashes []func() hash.Hash, path string) ([][]byte, error) {
+	if c == nil || path == digest.Stdin {
+		return digest.MultiFile(newHashes, path)
+	}
+	if len(algorithms) != len(newHashes) {
+		return nil, fmt.Errorf("%d algorithm names for %d hashes", len(algorithms), len(newHashes))
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	// pipes, devices and files that cannot be stat'd are hashed uncached,
+	// and digest.MultiFile reports any error
+	before, ok := stat(path)
+	if !ok {
+		return digest.MultiFile(newHashes, path)
+	}
+
+	cached := c.lookup(algorithms, abs, before)
+	if cached != nil && !c.Verify {
+		return cached, nil
+	}
+
+	sums, err := digest.MultiFile(newHashes, path)
+	if err != nil {
+		return nil, err
+	}
+	// a file that changed while it was read has a digest that matches
+	// neither its old nor its new attributes, so it is not cached
+	after, ok := stat(path)
+	if !ok || after != before {
+		return sums, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached != nil {
+		for i := range sums {
+			if !bytes.Equal(sums[i], cached[i]) {
+				c.mismatched = append(c.mismatched, path)
+				break
+			}
+		}
+	}
+	for i, a := range algorithms {
+		e := before
+		e.Sum = hex.EncodeToString(sums[i])
+		c.updated[key(a, abs)] = e
+	}
+	return sums, nil
+}
+
+// lookup returns the cached sums for every algorithm, or nil if any entry
+// is missing or stale.
+func (c *Cache) lookup(algorithms []string, abs string, attrs Entry) [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sums := make([][]byte, len(algorithms))
+	for i, a := range algorithms {
+		k := key(a, abs)
+		e, ok := c.updated[k]
+		if !ok {
+			e, ok = c.entries[k]
+		}
+		if !ok || !e.valid(attrs) {
+			return nil
+		}
+		sum, err := hex.DecodeString(e.Sum)
+		if err != nil {
+			return nil
+		}
+		sums[i] = sum
+	}
+	return sums
+}
+
+// valid reports whether the entry was recorded for a file with attrs.
+func (e Entry) valid(attrs Entry) bool {
+	return e.Size == attrs.Size && e.ModTime == attrs.ModTime && e.Inode == attrs.Inode
+}
+
+// key joins an algorithm and an absolute path. Algorithm names never contain
+// a colon, so the key is unambiguous.
+func key(algorithm, abs string) string {
+	return algorithm + ":" + abs
+}
+
+// stat returns the attributes an entry is keyed on, with no sum. It reports
+// false for anything but an existing regular file.
+func stat(path string) (Entry, bool) {
+	fi, err := os.Stat(path)
+	if err != nil || !fi.Mode().IsRegular() {
+		return Entry{}, false
+	}
+	return Entry{Size: fi.Size(), ModTime: fi.ModTime().UnixNano(), Inode: inode(fi)}, true
+}
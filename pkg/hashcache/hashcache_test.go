@@ -0,0 +1,198 @@
+package hashcache
+
+import (
+	"bytes"
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"hash"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// counter wraps a hash constructor and counts the files hashed with it, so
+// a test can tell a cache hit from a miss.
+type counter struct {
+	newHash func() hash.Hash
+	calls   int
+}
+
+func (c *counter) New() hash.Hash {
+	c.calls++
+	return c.newHash()
+}
+
+func write(t *testing.T, path, contents string, mtime time.Time) {
+	t.Helper()
+	if err := ioutil.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func open(t *testing.T) *Cache {
+	t.Helper()
+	c, err := Open(filepath.Join(t.TempDir(), fileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// sum hashes path through the cache and reports whether it was read.
+func sum(t *testing.T, c *Cache, algorithm string, h *counter, path string) ([]byte, bool) {
+	t.Helper()
+	before := h.calls
+	s, err := c.File(algorithm, h.New, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, h.calls != before
+}
+
+var mtime = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC) //nolint:gochecknoglobals
+
+func TestHit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f")
+	write(t, path, "hello", mtime)
+	c := open(t)
+	h := &counter{newHash: sha256.New}
+
+	first, read := sum(t, c, "sha256", h, path)
+	if !read {
+		t.Fatal("the first lookup did not read the file")
+	}
+	second, read := sum(t, c, "sha256", h, path)
+	if read {
+		t.Error("an unchanged file was read again")
+	}
+	if !bytes.Equal(first, second) {
+		t.Errorf("cached sum %x differs from %x", second, first)
+	}
+
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := Open(c.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, read := sum(t, reopened, "sha256", h, path); read {
+		t.Error("an unchanged file was read again after Save and Open")
+	}
+}
+
+func TestSameSizeNewMtimeMisses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f")
+	write(t, path, "hello", mtime)
+	c := open(t)
+	h := &counter{newHash: sha256.New}
+	old, _ := sum(t, c, "sha256", h, path)
+
+	write(t, path, "HELLO", mtime.Add(time.Second))
+	got, read := sum(t, c, "sha256", h, path)
+	if !read {
+		t.Fatal("a rewritten file with a new mtime was not read")
+	}
+	if bytes.Equal(got, old) {
+		t.Error("the stale sum was returned")
+	}
+}
+
+func TestNewSizeSameMtimeMisses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f")
+	write(t, path, "hello", mtime)
+	c := open(t)
+	h := &counter{newHash: sha256.New}
+	old, _ := sum(t, c, "sha256", h, path)
+
+	// the mtime is put back, so only the size tells the files apart
+	write(t, path, "hello, world", mtime)
+	got, read := sum(t, c, "sha256", h, path)
+	if !read {
+		t.Fatal("a rewritten file with a new size was not read")
+	}
+	if bytes.Equal(got, old) {
+		t.Error("the stale sum was returned")
+	}
+}
+
+func TestReplacedByRenameMisses(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "f")
+	write(t, path, "hello", mtime)
+	if attrs, ok := stat(path); !ok || attrs.Inode == 0 {
+		t.Skip("inode numbers are not available on this platform")
+	}
+	c := open(t)
+	h := &counter{newHash: sha256.New}
+	old, _ := sum(t, c, "sha256", h, path)
+
+	// same size and mtime, so only the inode tells the files apart. the
+	// replacement is created while the original exists so that it cannot
+	// reuse its inode
+	replacement := filepath.Join(dir, "g")
+	write(t, replacement, "HELLO", mtime)
+	if err := os.Rename(replacement, path); err != nil {
+		t.Fatal(err)
+	}
+
+	got, read := sum(t, c, "sha256", h, path)
+	if !read {
+		t.Fatal("a file replaced by rename was not read")
+	}
+	if bytes.Equal(got, old) {
+		t.Error("the stale sum was returned")
+	}
+}
+
+func TestAlgorithmIsPartOfTheKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f")
+	write(t, path, "hello", mtime)
+	c := open(t)
+	h256 := &counter{newHash: sha256.New}
+	h1 := &counter{newHash: sha1.New}
+
+	sum(t, c, "sha256", h256, path)
+	got, read := sum(t, c, "sha1", h1, path)
+	if !read {
+		t.Fatal("a different algorithm for the same path hit the cache")
+	}
+	if len(got) != sha1.Size {
+		t.Errorf("got a %d byte sum, want %d", len(got), sha1.Size)
+	}
+}
+
+func TestRelativeAndAbsolutePathsShareAnEntry(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "f")
+	write(t, path, "hello", mtime)
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	c := open(t)
+	h := &counter{newHash: sha256.New}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chdir(wd) }()
+
+	if _, read := sum(t, c, "sha256", h, "f"); !read {
+		t.Fatal("the first lookup did not read the file")
+	}
+	if _, read := sum(t, c, "sha256", h, path); read {
+		t.Error("the absolute path missed the entry made for the relative path")
+	}
+	if _, read := sum(t, c, "sha256", h, "./sub/../f"); read {
+		t.Error("an unclean relative path missed the entry")
+	}
+}
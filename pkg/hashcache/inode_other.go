@@ -0,0 +1,14 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package hashcache
+
+import (
+	"os"
+)
+
+// inode returns 0 where os.FileInfo does not expose an inode number, so
+// entries are keyed on path, size and mtime alone.
+func inode(os.FileInfo) uint64 {
+	return 0
+}
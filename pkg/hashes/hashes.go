@@ -0,0 +1,173 @@
+// Package hashes is a registry that maps algorithm names, as typed on the
+// command line, to hash.Hash constructors.
+package hashes
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"hash/adler32"
+	"hash/crc32"
+	"hash/crc64"
+	"hash/fnv"
+	"strings"
+
+	"github.com/schigh/str"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/blake2s"
+	"golang.org/x/crypto/sha3"
+)
+
+// Algorithm describes a registered hash.
+type Algorithm struct {
+	// Name is the canonical, lowercase name used to select the algorithm.
+	Name string
+	// Tag is the name used in BSD-style "TAG (file) = digest" lines.
+	Tag string
+	// New returns a new hash.
+	New func() hash.Hash
+	// Cryptographic reports whether the algorithm is designed to resist
+	// deliberate collisions. Checksums such as CRC32 only detect accidents.
+	Cryptographic bool
+}
+
+// Size returns the digest size in bytes.
+func (a Algorithm) Size() int {
+	return a.New().Size()
+}
+
+var (
+	registry []Algorithm          //nolint:gochecknoglobals
+	index    map[string]Algorithm //nolint:gochecknoglobals
+	aliases  = map[string]string{ //nolint:gochecknoglobals
+		"blake2b": "blake2b-512",
+		"blake2s": "blake2s-256",
+		"crc32":   "crc32-ieee",
+		"crc64":   "crc64-iso",
+		"sha3":    "sha3-256",
+	}
+)
+
+func init() {
+	crypto := []Algorithm{
+		{Name: "md5", Tag: "MD5", New: md5.New},
+		{Name: "sha1", Tag: "SHA1", New: sha1.New},
+		{Name: "sha224", Tag: "SHA224", New: sha256.New224},
+		{Name: "sha256", Tag: "SHA256", New: sha256.New},
+		{Name: "sha384", Tag: "SHA384", New: sha512.New384},
+		{Name: "sha512", Tag: "SHA512", New: sha512.New},
+		{Name: "sha512/224", Tag: "SHA512/224", New: sha512.New512_224},
+		{Name: "sha512/256", Tag: "SHA512/256", New: sha512.New512_256},
+		{Name: "sha3-224", Tag: "SHA3-224", New: sha3.New224},
+		{Name: "sha3-256", Tag: "SHA3-256", New: sha3.New256},
+		{Name: "sha3-384", Tag: "SHA3-384", New: sha3.New384},
+		{Name: "sha3-512", Tag: "SHA3-512", New: sha3.New512},
+		{Name: "blake2b-256", Tag: "BLAKE2b-256", New: unkeyed(blake2b.New256)},
+		{Name: "blake2b-384", Tag: "BLAKE2b-384", New: unkeyed(blake2b.New384)},
+		{Name: "blake2b-512", Tag: "BLAKE2b", New: unkeyed(blake2b.New512)},
+		{Name: "blake2s-256", Tag: "BLAKE2s", New: unkeyed(blake2s.New256)},
+	}
+	for i := range crypto {
+		crypto[i].Cryptographic = true
+	}
+
+	checksums := []Algorithm{
+		{Name: "fnv32", Tag: "FNV32", New: hash32(fnv.New32)},
+		{Name: "fnv32a", Tag: "FNV32A", New: hash32(fnv.New32a)},
+		{Name: "fnv64", Tag: "FNV64", New: hash64(fnv.New64)},
+		{Name: "fnv64a", Tag: "FNV64A", New: hash64(fnv.New64a)},
+		{Name: "fnv128", Tag: "FNV128", New: fnv.New128},
+		{Name: "fnv128a", Tag: "FNV128A", New: fnv.New128a},
+		{Name: "adler32", Tag: "ADLER32", New: hash32(adler32.New)},
+		// every polynomial that str.CRC32Options supports
+		{Name: "crc32-ieee", Tag: "CRC32", New: crc32Table(str.CRC32TypeDefault)},
+		{Name: "crc32-c", Tag: "CRC32C", New: crc32Table(str.CRC32TypeC)},
+		{Name: "crc32-k", Tag: "CRC32K", New: crc32Table(str.CRC32TypeK)},
+		{Name: "crc32-k2", Tag: "CRC32K2", New: crc32Table(str.CRC32TypeK2)},
+		{Name: "crc32-q", Tag: "CRC32Q", New: crc32Table(str.CRC32TypeQ)},
+		{Name: "crc64-iso", Tag: "CRC64-ISO", New: crc64Table(str.CRC64TypeISO)},
+		{Name: "crc64-ecma", Tag: "CRC64-ECMA", New: crc64Table(str.CRC64TypeECMA)},
+	}
+
+	registry = append(crypto, checksums...)
+	index = make(map[string]Algorithm, len(registry))
+	for _, a := range registry {
+		index[a.Name] = a
+	}
+}
+
+// Lookup returns the algorithm registered under name or one of its aliases.
+// Names are case-insensitive.
+func Lookup(name string) (Algorithm, error) {
+	n := strings.ToLower(strings.TrimSpace(name))
+	if canonical, ok := aliases[n]; ok {
+		n = canonical
+	}
+	a, ok := index[n]
+	if !ok {
+		return Algorithm{}, fmt.Errorf("'%s' is not a supported hash algorithm. use one of: %s", name, strings.Join(Names(), ", "))
+	}
+	return a, nil
+}
+
+// LookupTag returns the algorithm whose BSD-style tag is tag, falling back to
+// Lookup so that lines tagged with a name, such as "sha256", are accepted
+// too. Tags are case-insensitive.
+func LookupTag(tag string) (Algorithm, error) {
+	for _, a := range registry {
+		if strings.EqualFold(a.Tag, tag) {
+			return a, nil
+		}
+	}
+	return Lookup(tag)
+}
+
+// All returns every registered algorithm, cryptographic hashes first.
+func All() []Algorithm {
+	out := make([]Algorithm, len(registry))
+	copy(out, registry)
+	return out
+}
+
+// Names returns the canonical name of every registered algorithm, in the
+// same order as All.
+func Names() []string {
+	out := make([]string, len(registry))
+	for i, a := range registry {
+		out[i] = a.Name
+	}
+	return out
+}
+
+// unkeyed adapts the blake2 constructors, which only fail for invalid keys,
+// to the hash.Hash constructor signature.
+func unkeyed(fn func(key []byte) (hash.Hash, error)) func() hash.Hash {
+	return func() hash.Hash {
+		h, err := fn(nil)
+		if err != nil {
+			panic(err)
+		}
+		return h
+	}
+}
+
+func hash32(fn func() hash.Hash32) func() hash.Hash {
+	return func() hash.Hash { return fn() }
+}
+
+func hash64(fn func() hash.Hash64) func() hash.Hash {
+	return func() hash.Hash { return fn() }
+}
+
+func crc32Table(poly uint32) func() hash.Hash {
+	table := crc32.MakeTable(poly)
+	return func() hash.Hash { return crc32.New(table) }
+}
+
+func crc64Table(poly uint64) func() hash.Hash {
+	table := crc64.MakeTable(poly)
+	return func() hash.Hash { return crc64.New(table) }
+}
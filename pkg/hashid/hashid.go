@@ -0,0 +1,391 @@
+// Package hashid guesses what produced an opaque hash or identifier string
+// from its length, character set and structure.
+//
+// Identification is heuristic. Most digests are indistinguishable random
+// bytes, so a 64 character hex string could be SHA-256, SHA3-256 or
+// BLAKE2s; Identify returns every plausible format ranked by how common it
+// is, with the reasons it was considered. Structured formats, such as
+// bcrypt hashes, PHC strings and UUIDs, are validated with the same parsers
+// the tools that produce them use, and rank above bare digests.
+package hashid
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/schigh/tools/pkg/cuid"
+	"github.com/schigh/tools/pkg/hashes"
+	"github.com/schigh/tools/pkg/typeid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Scores. A structured match is near certain; a digest of the right size is
+// only as likely as the algorithm is common.
+const (
+	scoreCertain  = 95
+	scoreLikely   = 80
+	scoreCommon   = 60
+	scorePossible = 40
+	scoreUnlikely = 20
+)
+
+// Candidate is one possible format for a string.
+type Candidate struct {
+	// Format names the format, using the hashes registry name for digests,
+	// such as "sha256".
+	Format string `json:"format"`
+	// Score ranks candidates from 0 to 100. It is not a probability.
+	Score int `json:"score"`
+	// Reasons explains why the format was considered.
+	Reasons []string `json:"reasons"`
+}
+
+// common are the digests most often seen in the wild, which rank above
+// others of the same size.
+var common = map[string]bool{ //nolint:gochecknoglobals
+	"md5":        true,
+	"sha1":       true,
+	"sha224":     true,
+	"sha256":     true,
+	"sha384":     true,
+	"sha512":     true,
+	"crc32-ieee": true,
+	"crc64-iso":  true,
+}
+
+// Identify returns the candidate formats for s, best first. It returns an
+// empty slice when nothing matches.
+func Identify(s string) []Candidate {
+	s = strings.TrimSpace(s)
+	var out []Candidate
+	for _, fn := range []func(string) []Candidate{
+		identifyBcrypt,
+		identifyCrypt,
+		identifyUUID,
+		identifyTypeID,
+		identifyCUID,
+		identifyHex,
+		identifyBase64,
+		identifyDecimal,
+	} {
+		out = append(out, fn(s)...)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].Format < out[j].Format
+	})
+	if out == nil {
+		out = []Candidate{}
+	}
+	return out
+}
+
+func identifyBcrypt(s string) []Candidate {
+	if !strings.HasPrefix(s, "$2") {
+		return nil
+	}
+	cost, err := bcrypt.Cost([]byte(s))
+	if err != nil {
+		return []Candidate{{
+			Format:  "bcrypt",
+			Score:   scoreUnlikely,
+			Reasons: []string{"starts with a bcrypt $2 prefix", fmt.Sprintf("but does not parse: %v", err)},
+		}}
+	}
+	// bcrypt.Cost only checks the prefix and cost, so check the rest too
+	reasons := []string{
+		fmt.Sprintf("%s prefix", s[:strings.Index(s[1:], "$")+2]),
+		fmt.Sprintf("cost %d", cost),
+	}
+	score := scoreCertain
+	if len(s) == 60 {
+		reasons = append(reasons, "60 characters, the length of a bcrypt hash")
+	} else {
+		reasons = append(reasons, fmt.Sprintf("%d characters, but bcrypt hashes are 60", len(s)))
+		score = scorePossible
+	}
+	return []Candidate{{Format: "bcrypt", Score: score, Reasons: reasons}}
+}
+
+// identifyCrypt recognises PHC string format hashes
+// (https://github.com/P-H-C/phc-string-format) and the older modular crypt
+// formats that share the leading $id$.
+func identifyCrypt(s string) []Candidate {
+	if !strings.HasPrefix(s, "$") || strings.HasPrefix(s, "$2") {
+		return nil
+	}
+	fields := strings.Split(s[1:], "$")
+	id := fields[0]
+	params := phcParams(fields[1:])
+
+	switch {
+	case strings.HasPrefix(id, "argon2"):
+		reasons := []string{fmt.Sprintf("PHC string with id '%s'", id)}
+		score := scoreCertain
+		for _, k := range []string{"m", "t", "p"} {
+			if _, ok := params[k]; ok {
+				continue
+			}
+			reasons = append(reasons, fmt.Sprintf("missing the %s parameter", k))
+			score = scorePossible
+		}
+		if v, ok := params["v"]; ok {
+			reasons = append(reasons, "version "+v)
+		}
+		return []Candidate{{Format: id, Score: score, Reasons: reasons}}
+	case id == "scrypt":
+		reasons := []string{"PHC string with id 'scrypt'"}
+		if ln, ok := params["ln"]; ok {
+			reasons = append(reasons, "log2(N) "+ln)
+		}
+		return []Candidate{{Format: "scrypt", Score: scoreCertain, Reasons: reasons}}
+	case strings.HasPrefix(id, "pbkdf2"):
+		reasons := []string{fmt.Sprintf("PHC string with id '%s'", id)}
+		if i, ok := params["i"]; ok {
+			reasons = append(reasons, i+" iterations")
+		} else if len(fields) > 1 {
+			// passlib writes the iteration count as a bare field
+			if _, err := strconv.Atoi(fields[1]); err == nil {
+				reasons = append(reasons, fields[1]+" iterations")
+			}
+		}
+		return []Candidate{{Format: id, Score: scoreCertain, Reasons: reasons}}
+	}
+
+	crypts := map[string]string{
+		"1":  "md5crypt",
+		"5":  "sha256crypt",
+		"6":  "sha512crypt",
+		"y":  "yescrypt",
+		"7":  "scrypt",
+		"gy": "gost-yescrypt",
+	}
+	if format, ok := crypts[id]; ok {
+		return []Candidate{{
+			Format:  format,
+			Score:   scoreLikely,
+			Reasons: []string{fmt.Sprintf("modular crypt format with id '$%s$'", id)},
+		}}
+	}
+	return []Candidate{{
+		Format:  "phc",
+		Score:   scoreUnlikely,
+		Reasons: []string{fmt.Sprintf("$-delimited like a PHC string, but '%s' is not a known id", id)},
+	}}
+}
+
+// phcParams collects the key=value pairs from the fields of a PHC string.
+func phcParams(fields []string) map[string]string {
+	out := map[string]string{}
+	for _, f := range fields {
+		for _, kv := range strings.Split(f, ",") {
+			if k, v, ok := cut(kv, "="); ok {
+				out[k] = v
+			}
+		}
+	}
+	return out
+}
+
+func identifyUUID(s string) []Candidate {
+	if len(s) != 36 && len(s) != 32 && len(s) != 38 && len(s) != 45 {
+		return nil
+	}
+	u, err := uuid.Parse(s)
+	if err != nil {
+		return nil
+	}
+	reasons := []string{
+		fmt.Sprintf("parses as a UUID, version %d", u.Version()),
+		fmt.Sprintf("variant %s", u.Variant()),
+	}
+	score := scoreCertain
+	if len(s) == 32 {
+		// 32 hex digits is also every 128 bit digest
+		reasons = append(reasons, "but has no hyphens, so it may be any 128 bit value")
+		score = scorePossible
+	}
+	if u.Variant() != uuid.RFC4122 {
+		score = scoreUnlikely
+	}
+	if u.Version() == 1 {
+		sec, nsec := u.Time().UnixTime()
+		reasons = append(reasons, "created "+time.Unix(sec, nsec).UTC().Format(time.RFC3339))
+	}
+	return []Candidate{{Format: fmt.Sprintf("uuid-v%d", u.Version()), Score: score, Reasons: reasons}}
+}
+
+func identifyTypeID(s string) []Candidate {
+	if !strings.Contains(s, "_") && len(s) != 26 {
+		return nil
+	}
+	id, err := typeid.Parse(s)
+	if err != nil {
+		return nil
+	}
+	reasons := []string{"parses as a TypeID"}
+	if id.Prefix() != "" {
+		reasons = append(reasons, fmt.Sprintf("type prefix '%s'", id.Prefix()))
+	}
+	reasons = append(reasons, "created "+id.Time().UTC().Format(time.RFC3339))
+	score := scoreLikely
+	if id.Prefix() == "" {
+		score = scorePossible
+	}
+	return []Candidate{{Format: "typeid", Score: score, Reasons: reasons}}
+}
+
+func identifyCUID(s string) []Candidate {
+	c, err := cuid.ParseString(s)
+	if err != nil {
+		return nil
+	}
+	created := c.Time()
+	reasons := []string{"25 characters starting with 'c' that parse as a CUID"}
+	score := scoreLikely
+	// CUIDs are timestamped, so one from before the format existed or from
+	// the future is probably something else
+	if created.Year() < 2012 || created.After(time.Now().AddDate(1, 0, 0)) {
+		reasons = append(reasons, "but its timestamp "+created.UTC().Format(time.RFC3339)+" is implausible")
+		score = scoreUnlikely
+	} else {
+		reasons = append(reasons, "created "+created.UTC().Format(time.RFC3339))
+	}
+	return []Candidate{{Format: "cuid", Score: score, Reasons: reasons}}
+}
+
+// identifyHex matches hex strings against every registered digest size.
+func identifyHex(s string) []Candidate {
+	if len(s) == 0 || len(s)%2 != 0 || strings.Trim(strings.ToLower(s), "0123456789abcdef") != "" {
+		return nil
+	}
+	casing := "lowercase"
+	if strings.ToUpper(s) == s && strings.ToLower(s) != s {
+		casing = "uppercase"
+	}
+
+	var out []Candidate
+	for _, a := range hashes.All() {
+		if a.Size()*2 != len(s) {
+			continue
+		}
+		reasons := []string{
+			fmt.Sprintf("%d %s hex characters, the size of a %d byte %s digest", len(s), casing, a.Size(), a.Name),
+		}
+		score := scorePossible
+		if common[a.Name] {
+			score = scoreCommon
+			reasons = append(reasons, a.Name+" is the most common algorithm of this size")
+		}
+		if len(s) == 32 {
+			// a bare 128 bit value is just as likely to be a UUID
+			score -= 5
+		}
+		out = append(out, Candidate{Format: a.Name, Score: score, Reasons: reasons})
+	}
+	return out
+}
+
+// identifyBase64 matches base64 strings, including SRI values, against
+// every registered digest size.
+func identifyBase64(s string) []Candidate {
+	sri := ""
+	if i := strings.IndexByte(s, '-'); i > 0 {
+		switch s[:i] {
+		case "sha256", "sha384", "sha512":
+			sri, s = s[:i], s[i+1:]
+		}
+	}
+	if len(s) < 8 || strings.Trim(strings.ToLower(s), "0123456789abcdef") == "" {
+		// too short to say, or hex, which identifyHex handles
+		return nil
+	}
+
+	var sum []byte
+	encoding := ""
+	for _, e := range []struct {
+		name string
+		enc  *base64.Encoding
+	}{
+		{"base64", base64.StdEncoding},
+		{"unpadded base64", base64.RawStdEncoding},
+		{"base64url", base64.URLEncoding},
+		{"unpadded base64url", base64.RawURLEncoding},
+	} {
+		if b, err := e.enc.DecodeString(s); err == nil {
+			sum, encoding = b, e.name
+			break
+		}
+	}
+	if sum == nil {
+		return nil
+	}
+
+	var out []Candidate
+	for _, a := range hashes.All() {
+		if a.Size() != len(sum) {
+			continue
+		}
+		if sri != "" {
+			if a.Name != sri {
+				continue
+			}
+			out = append(out, Candidate{
+				Format:  a.Name,
+				Score:   scoreCertain,
+				Reasons: []string{fmt.Sprintf("subresource integrity value with a %d byte %s digest", len(sum), sri)},
+			})
+			continue
+		}
+		score := scoreUnlikely
+		if common[a.Name] {
+			score = scorePossible
+		}
+		out = append(out, Candidate{
+			Format:  a.Name,
+			Score:   score,
+			Reasons: []string{fmt.Sprintf("%s of %d bytes, the size of a %s digest", encoding, len(sum), a.Name)},
+		})
+	}
+	return out
+}
+
+// identifyDecimal matches the decimal checksums str.CRC32 and str.CRC64
+// print.
+func identifyDecimal(s string) []Candidate {
+	if len(s) == 0 || strings.Trim(s, "0123456789") != "" {
+		return nil
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return nil
+	}
+	if v <= 1<<32-1 {
+		return []Candidate{{
+			Format:  "crc32-decimal",
+			Score:   scorePossible,
+			Reasons: []string{"decimal number that fits in 32 bits, as str.CRC32 prints"},
+		}}
+	}
+	return []Candidate{{
+		Format:  "crc64-decimal",
+		Score:   scorePossible,
+		Reasons: []string{"decimal number that fits in 64 bits, as str.CRC64 prints"},
+	}}
+}
+
+// cut is strings.Cut, which needs Go 1.18.
+func cut(s, sep string) (string, string, bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
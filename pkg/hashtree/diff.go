@@ -0,0 +1,89 @@
+package hashtree
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ChangeKind describes how a file differs between two manifests.
+type ChangeKind string
+
+// Change kinds.
+const (
+	Added   ChangeKind = "added"
+	Removed ChangeKind = "removed"
+	Changed ChangeKind = "changed"
+)
+
+// Change is a file that differs between two manifests.
+type Change struct {
+	Kind ChangeKind
+	Path string
+	// Fields lists what changed for a Changed file: type, size, mode,
+	// digest or target.
+	Fields []string
+}
+
+func (c Change) String() string {
+	if c.Kind == Changed {
+		return fmt.Sprintf("%s %s (%s)", c.Kind, c.Path, strings.Join(c.Fields, ", "))
+	}
+	return fmt.Sprintf("%s %s", c.Kind, c.Path)
+}
+
+// Diff returns the files added, removed and changed in to relative to from,
+// sorted by path. Both manifests must use the same algorithm, since digests
+// of different algorithms cannot be compared.
+func Diff(from, to *Manifest) ([]Change, error) {
+	if !strings.EqualFold(from.Algorithm, to.Algorithm) {
+		return nil, fmt.Errorf("cannot compare a %s manifest with a %s manifest", from.Algorithm, to.Algorithm)
+	}
+
+	before := make(map[string]File, len(from.Files))
+	for _, f := range from.Files {
+		before[f.Path] = f
+	}
+
+	var changes []Change
+	seen := make(map[string]bool, len(to.Files))
+	for _, f := range to.Files {
+		seen[f.Path] = true
+		prev, ok := before[f.Path]
+		if !ok {
+			changes = append(changes, Change{Kind: Added, Path: f.Path})
+			continue
+		}
+		if fields := compare(prev, f); len(fields) > 0 {
+			changes = append(changes, Change{Kind: Changed, Path: f.Path, Fields: fields})
+		}
+	}
+	for _, f := range from.Files {
+		if !seen[f.Path] {
+			changes = append(changes, Change{Kind: Removed, Path: f.Path})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+func compare(a, b File) []string {
+	var fields []string
+	if a.Type != b.Type {
+		fields = append(fields, "type")
+	}
+	if a.Size != b.Size {
+		fields = append(fields, "size")
+	}
+	if a.Mode != b.Mode {
+		fields = append(fields, "mode")
+	}
+	if !strings.EqualFold(a.Digest, b.Digest) {
+		fields = append(fields, "digest")
+	}
+	if a.Target != b.Target {
+		fields = append(fields, "target")
+	}
+	return fields
+}
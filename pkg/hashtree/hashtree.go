@@ -0,0 +1,371 @@
+// Package hashtree hashes a directory tree into a manifest of every file's
+// path, size, mode and digest, and a single Merkle root that changes if any
+// of them do. The walk is deterministic, so two byte-identical trees produce
+// the same manifest and root on any machine.
+package hashtree
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/schigh/tools/pkg/digest"
+	"github.com/schigh/tools/pkg/hashcache"
+	"github.com/schigh/tools/pkg/hashes"
+)
+
+// Symlinks selects how symbolic links are handled.
+type Symlinks int
+
+const (
+	// SymlinksRecord adds a link to the manifest without following it. Its
+	// digest is the hash of the link target, so retargeting a link changes
+	// the root but the file it points to does not.
+	SymlinksRecord Symlinks = iota
+	// SymlinksFollow hashes whatever a link points to as if it were in the
+	// tree. Links to directories are descended into; a link back to a
+	// directory already being walked is an error.
+	SymlinksFollow
+	// SymlinksSkip leaves links out of the manifest.
+	SymlinksSkip
+)
+
+// ParseSymlinks converts a symlink mode name (record, follow or skip) into a
+// Symlinks.
+func ParseSymlinks(s string) (Symlinks, error) {
+	switch strings.ToLower(s) {
+	case "", "record":
+		return SymlinksRecord, nil
+	case "follow":
+		return SymlinksFollow, nil
+	case "skip":
+		return SymlinksSkip, nil
+	default:
+		return SymlinksRecord, fmt.Errorf("'%s' is an invalid symlink mode. use record, follow or skip", s)
+	}
+}
+
+func (s Symlinks) String() string {
+	switch s {
+	case SymlinksRecord:
+		return "record"
+	case SymlinksFollow:
+		return "follow"
+	case SymlinksSkip:
+		return "skip"
+	default:
+		return fmt.Sprintf("Symlinks(%d)", int(s))
+	}
+}
+
+// Options configure Build.
+type Options struct {
+	// Algorithm hashes file contents and the Merkle tree. It defaults to
+	// sha256.
+	Algorithm hashes.Algorithm
+	// Ignore lists path.Match patterns. A pattern is matched against both the
+	// slash separated path relative to the root and the base name, so "*.log"
+	// ignores log files at any depth and "build/tmp" only that directory.
+	// Ignoring a directory ignores everything under it.
+	Ignore []string
+	// Symlinks selects how symbolic links are handled.
+	Symlinks Symlinks
+	// Workers bounds the number of files hashed at once. It defaults to the
+	// number of CPUs.
+	Workers int
+	// Cache, if not nil, supplies the digests of files that have not
+	// changed since they were last hashed.
+	Cache *hashcache.Cache
+}
+
+// Entry types.
+const (
+	TypeFile    = "file"
+	TypeSymlink = "symlink"
+)
+
+// File is one entry in a manifest.
+type File struct {
+	// Path is slash separated and relative to the root of the tree.
+	Path string `json:"path"`
+	// Type is TypeFile or TypeSymlink. Followed links are recorded as files.
+	Type string `json:"type"`
+	// Size is the file size in bytes, or the length of a link's target.
+	Size int64 `json:"size"`
+	// Mode is the permission bits in octal, such as "0644".
+	Mode string `json:"mode"`
+	// Digest is the hex encoded hash of the file contents, or of the link
+	// target for a recorded link.
+	Digest string `json:"digest"`
+	// Target is the target of a recorded link.
+	Target string `json:"target,omitempty"`
+}
+
+// Manifest describes every file in a tree.
+type Manifest struct {
+	// Algorithm is the name of the hash used for files and the root.
+	Algorithm string `json:"algorithm"`
+	// Root is the hex encoded Merkle root of Files. See Root.
+	Root string `json:"root"`
+	// Files is sorted by Path.
+	Files []File `json:"files"`
+}
+
+// Build walks dir and returns its manifest.
+func Build(dir string, opts Options) (*Manifest, error) {
+	if opts.Algorithm.New == nil {
+		a, err := hashes.Lookup("sha256")
+		if err != nil {
+			return nil, err
+		}
+		opts.Algorithm = a
+	}
+	if opts.Workers < 1 {
+		opts.Workers = runtime.NumCPU()
+	}
+	for _, p := range opts.Ignore {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("'%s' is an invalid ignore pattern: %w", p, err)
+		}
+	}
+
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("'%s' is not a directory", dir)
+	}
+
+	w := walker{opts: opts, active: map[string]bool{}}
+	if err := w.walk(dir, ""); err != nil {
+		return nil, err
+	}
+	if err := hashFiles(w.jobs, opts); err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{Algorithm: opts.Algorithm.Name, Files: make([]File, len(w.jobs))}
+	for i, j := range w.jobs {
+		m.Files[i] = j.file
+	}
+	sort.Slice(m.Files, func(i, j int) bool { return m.Files[i].Path < m.Files[j].Path })
+	for i := 1; i < len(m.Files); i++ {
+		if m.Files[i].Path == m.Files[i-1].Path {
+			return nil, fmt.Errorf("'%s' appears twice in the tree", m.Files[i].Path)
+		}
+	}
+
+	root, err := Root(opts.Algorithm, m.Files)
+	if err != nil {
+		return nil, err
+	}
+	m.Root = hex.EncodeToString(root)
+	return m, nil
+}
+
+// job is a file waiting to be hashed. Recorded links are complete when they
+// are found and have an empty source.
+type job struct {
+	source string
+	file   File
+}
+
+type walker struct {
+	opts Options
+	jobs []job
+	// active holds the real paths of the directories being walked, to catch
+	// followed links that loop back on themselves.
+	active map[string]bool
+}
+
+func (w *walker) walk(dir, rel string) error {
+	real, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	if w.active[real] {
+		return fmt.Errorf("'%s' links back to a directory that is already being walked", dir)
+	}
+	w.active[real] = true
+	defer delete(w.active, real)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	// os.ReadDir sorts by name, which is what makes the walk deterministic
+	for _, e := range entries {
+		name := e.Name()
+		full := filepath.Join(dir, name)
+		relPath := path.Join(rel, name)
+		if w.ignored(relPath, name) {
+			continue
+		}
+
+		info, err := os.Lstat(full)
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			switch w.opts.Symlinks {
+			case SymlinksSkip:
+				continue
+			case SymlinksRecord:
+				if err := w.recordLink(full, relPath); err != nil {
+					return err
+				}
+				continue
+			case SymlinksFollow:
+				if info, err = os.Stat(full); err != nil {
+					return err
+				}
+			}
+		}
+
+		switch {
+		case info.IsDir():
+			if err := w.walk(full, relPath); err != nil {
+				return err
+			}
+		case info.Mode().IsRegular():
+			w.jobs = append(w.jobs, job{
+				source: full,
+				file: File{
+					Path: relPath,
+					Type: TypeFile,
+					Size: info.Size(),
+					Mode: mode(info.Mode()),
+				},
+			})
+		default:
+			return fmt.Errorf("'%s' is not a regular file, directory or symlink", full)
+		}
+	}
+	return nil
+}
+
+func (w *walker) recordLink(full, rel string) error {
+	target, err := os.Readlink(full)
+	if err != nil {
+		return err
+	}
+	target = filepath.ToSlash(target)
+	sum, err := digest.Reader(w.opts.Algorithm.New, strings.NewReader(target))
+	if err != nil {
+		return err
+	}
+	w.jobs = append(w.jobs, job{file: File{
+		Path:   rel,
+		Type:   TypeSymlink,
+		Size:   int64(len(target)),
+		Mode:   mode(0o777),
+		Digest: hex.EncodeToString(sum),
+		Target: target,
+	}})
+	return nil
+}
+
+func (w *walker) ignored(rel, name string) bool {
+	for _, p := range w.opts.Ignore {
+		if ok, _ := path.Match(p, rel); ok {
+			return true
+		}
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hashFiles fills in the digest of every job with a source, using at most
+// opts.Workers goroutines. It returns the first error encountered.
+func hashFiles(jobs []job, opts Options) error {
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+		queue = make(chan int)
+	)
+	wg.Add(opts.Workers)
+	for i := 0; i < opts.Workers; i++ {
+		go func() {
+			defer wg.Done()
+			for idx := range queue {
+				sum, err := opts.Cache.File(opts.Algorithm.Name, opts.Algorithm.New, jobs[idx].source)
+				if err != nil {
+					once.Do(func() { first = err })
+					continue
+				}
+				jobs[idx].file.Digest = hex.EncodeToString(sum)
+			}
+		}()
+	}
+	for i := range jobs {
+		if jobs[i].source != "" {
+			queue <- i
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	return first
+}
+
+func mode(m os.FileMode) string {
+	return fmt.Sprintf("%04o", m.Perm())
+}
+
+// Read decodes a JSON manifest.
+func Read(r io.Reader) (*Manifest, error) {
+	var m Manifest
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, fmt.Errorf("unable to read manifest: %w", err)
+	}
+	if _, err := hashes.Lookup(m.Algorithm); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// ReadFile decodes the JSON manifest at path.
+func ReadFile(path string) (*Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return Read(f)
+}
+
+// Write encodes m as indented JSON.
+func (m *Manifest) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+// Verify recomputes the manifest's root from its files and returns an error
+// if it does not match, which means the manifest was edited or truncated.
+func (m *Manifest) Verify() error {
+	a, err := hashes.Lookup(m.Algorithm)
+	if err != nil {
+		return err
+	}
+	root, err := Root(a, m.Files)
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(root) != strings.ToLower(m.Root) {
+		return fmt.Errorf("manifest root %s does not match its files", m.Root)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package hashtree
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+
+	"github.com/schigh/tools/pkg/hashes"
+)
+
+// Domain separation prefixes, as in RFC 6962, so that a leaf can never be
+// mistaken for an interior node.
+const (
+	leafPrefix = 0x00
+	nodePrefix = 0x01
+)
+
+// Root returns the Merkle root of files, which must be sorted by path. It
+// follows the Merkle Tree Hash of RFC 6962 section 2.1:
+//
+//	root([])     = H()
+//	root([f])    = H(0x00 || leaf(f))
+//	root(files)  = H(0x01 || root(files[:k]) || root(files[k:]))
+//
+// where k is the largest power of two smaller than len(files), and leaf(f)
+// is the path, type, mode and link target of f, each prefixed with its length
+// as a big endian uint32 so that no two files encode the same way, followed
+// by the size and the raw digest.
+func Root(a hashes.Algorithm, files []File) ([]byte, error) {
+	if len(files) == 0 {
+		return a.New().Sum(nil), nil
+	}
+	leaves := make([][]byte, len(files))
+	for i, f := range files {
+		leaf, err := leafHash(a.New(), f)
+		if err != nil {
+			return nil, err
+		}
+		leaves[i] = leaf
+	}
+	return merkle(a, leaves), nil
+}
+
+func leafHash(h hash.Hash, f File) ([]byte, error) {
+	sum, err := hex.DecodeString(f.Digest)
+	if err != nil {
+		return nil, fmt.Errorf("'%s' has an invalid digest: %w", f.Path, err)
+	}
+	_, _ = h.Write([]byte{leafPrefix})
+	for _, s := range []string{f.Path, f.Type, f.Mode, f.Target} {
+		writeString(h, s)
+	}
+	var size [8]byte
+	binary.BigEndian.PutUint64(size[:], uint64(f.Size))
+	_, _ = h.Write(size[:])
+	_, _ = h.Write(sum)
+	return h.Sum(nil), nil
+}
+
+func writeString(h hash.Hash, s string) {
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], uint32(len(s)))
+	_, _ = h.Write(n[:])
+	_, _ = h.Write([]byte(s))
+}
+
+func merkle(a hashes.Algorithm, nodes [][]byte) []byte {
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+	k := 1
+	for k*2 < len(nodes) {
+		k *= 2
+	}
+	h := a.New()
+	_, _ = h.Write([]byte{nodePrefix})
+	_, _ = h.Write(merkle(a, nodes[:k]))
+	_, _ = h.Write(merkle(a, nodes[k:]))
+	return h.Sum(nil)
+}
@@ -0,0 +1,177 @@
+package htpasswd
+
+import (
+	"crypto/md5"  //nolint:gosec // apr1 is only verified, never written
+	"crypto/sha1" //nolint:gosec // {SHA} is only verified, never written
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Algorithm names a password hash format found in htpasswd files.
+type Algorithm string
+
+// The formats Apache's htpasswd can write. Only Bcrypt is considered secure;
+// the rest are fast enough to guess offline at billions of tries a second.
+const (
+	Bcrypt      Algorithm = "bcrypt"
+	APR1        Algorithm = "apr1"
+	SHA1        Algorithm = "sha1"
+	SHA256Crypt Algorithm = "sha256-crypt"
+	SHA512Crypt Algorithm = "sha512-crypt"
+	Crypt       Algorithm = "crypt"
+	Unknown     Algorithm = "unknown"
+)
+
+// ErrLegacy is returned when verifying against an insecure format without
+// allowing it.
+var ErrLegacy = errors.New("the hash uses an insecure legacy format") //nolint:gochecknoglobals
+
+// bcryptPrefix is the version Apache writes. Go's bcrypt writes $2a$, which
+// is the same algorithm; $2y$ only exists to tell the two apart from a
+// buggy PHP implementation, and every reader accepts it.
+const bcryptPrefix = "$2y$"
+
+const cryptAlphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// Identify returns the format of hash.
+func Identify(hash string) Algorithm {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return Bcrypt
+	case strings.HasPrefix(hash, "$apr1$"):
+		return APR1
+	case strings.HasPrefix(hash, "{SHA}"):
+		return SHA1
+	case strings.HasPrefix(hash, "$5$"):
+		return SHA256Crypt
+	case strings.HasPrefix(hash, "$6$"):
+		return SHA512Crypt
+	case len(hash) == 13 && strings.Trim(hash, cryptAlphabet) == "":
+		return Crypt
+	}
+	return Unknown
+}
+
+// Secure reports whether a is safe to keep using.
+func (a Algorithm) Secure() bool {
+	return a == Bcrypt
+}
+
+// Hash returns a bcrypt hash of secret in the form Apache writes.
+func Hash(secret []byte, cost int) (string, error) {
+	h, err := bcrypt.GenerateFromPassword(secret, cost)
+	if err != nil {
+		return "", err
+	}
+	return bcryptPrefix + string(h[len(bcryptPrefix):]), nil
+}
+
+// Verify reports whether secret matches hash. Legacy formats return
+// ErrLegacy unless allowLegacy is set, and even then only apr1 and {SHA} can
+// be checked.
+func Verify(hash string, secret []byte, allowLegacy bool) (bool, error) {
+	alg := Identify(hash)
+	if alg == Bcrypt {
+		err := bcrypt.CompareHashAndPassword([]byte(hash), secret)
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	if !allowLegacy {
+		return false, fmt.Errorf("%w: %s", ErrLegacy, alg)
+	}
+
+	var want string
+	switch alg {
+	case APR1:
+		salt := strings.TrimPrefix(hash, "$apr1$")
+		if i := strings.IndexByte(salt, '$'); i >= 0 {
+			salt = salt[:i]
+		}
+		want = apr1(secret, salt)
+	case SHA1:
+		sum := sha1.Sum(secret) //nolint:gosec
+		want = "{SHA}" + base64.StdEncoding.EncodeToString(sum[:])
+	default:
+		return false, fmt.Errorf("%s hashes cannot be verified", alg)
+	}
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(want)) == 1, nil
+}
+
+// apr1 is Apache's variant of the FreeBSD MD5 crypt, differing only in its
+// magic string.
+func apr1(secret []byte, salt string) string {
+	const magic = "$apr1$"
+	if len(salt) > 8 {
+		salt = salt[:8]
+	}
+
+	alt := md5.New() //nolint:gosec
+	alt.Write(secret)
+	alt.Write([]byte(salt))
+	alt.Write(secret)
+	altSum := alt.Sum(nil)
+
+	ctx := md5.New() //nolint:gosec
+	ctx.Write(secret)
+	ctx.Write([]byte(magic))
+	ctx.Write([]byte(salt))
+	for n := len(secret); n > 0; n -= 16 {
+		if n > 16 {
+			ctx.Write(altSum)
+		} else {
+			ctx.Write(altSum[:n])
+		}
+	}
+	for i := len(secret); i > 0; i >>= 1 {
+		if i&1 == 1 {
+			ctx.Write([]byte{0})
+		} else {
+			ctx.Write(secret[:1])
+		}
+	}
+	sum := ctx.Sum(nil)
+
+	// the thousand rounds are there to slow guessing down, which was
+	// enough in 1994
+	for i := 0; i < 1000; i++ {
+		round := md5.New() //nolint:gosec
+		if i&1 == 1 {
+			round.Write(secret)
+		} else {
+			round.Write(sum)
+		}
+		if i%3 != 0 {
+			round.Write([]byte(salt))
+		}
+		if i%7 != 0 {
+			round.Write(secret)
+		}
+		if i&1 == 1 {
+			round.Write(sum)
+		} else {
+			round.Write(secret)
+		}
+		sum = round.Sum(nil)
+	}
+
+	var b strings.Builder
+	b.WriteString(magic + salt + "$")
+	encode := func(v uint32, n int) {
+		for ; n > 0; n-- {
+			b.WriteByte(cryptAlphabet[v&0x3f])
+			v >>= 6
+		}
+	}
+	for _, g := range [][3]int{{0, 6, 12}, {1, 7, 13}, {2, 8, 14}, {3, 9, 15}, {4, 10, 5}} {
+		encode(uint32(sum[g[0]])<<16|uint32(sum[g[1]])<<8|uint32(sum[g[2]]), 4)
+	}
+	encode(uint32(sum[11]), 2)
+	return b.String()
+}
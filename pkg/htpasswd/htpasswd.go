@@ -0,0 +1,232 @@
+// Package htpasswd reads and edits Apache htpasswd files.
+//
+// A file is a list of user:hash lines. Comments, blank lines and anything
+// else that is not an entry are kept exactly as they were, so editing a file
+// by hand and with this package can be mixed freely. New hashes are always
+// bcrypt; other formats are recognised so they can be listed, and replaced.
+package htpasswd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/schigh/tools/pkg/filelock"
+)
+
+// newFileMode is the mode of files Update creates. Web servers usually read
+// htpasswd files through their group.
+const newFileMode = 0o640
+
+// Entry is a user's line in a file.
+type Entry struct {
+	User      string    `json:"user"`
+	Hash      string    `json:"-"`
+	Algorithm Algorithm `json:"algorithm"`
+	// Line is the 1-based line number of the entry.
+	Line int `json:"line"`
+}
+
+type line struct {
+	text string
+	// user is empty for lines that are not entries
+	user string
+	hash string
+}
+
+// File is the contents of an htpasswd file.
+type File struct {
+	lines []line
+}
+
+// Parse reads a file. Lines that are not user:hash entries are kept as they
+// are rather than rejected, as Apache ignores them.
+func Parse(r io.Reader) (*File, error) {
+	f := &File{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		text := scanner.Text()
+		l := line{text: text}
+		trimmed := strings.TrimRight(text, "\r")
+		if !strings.HasPrefix(strings.TrimSpace(trimmed), "#") {
+			if i := strings.IndexByte(trimmed, ':'); i > 0 {
+				l.user, l.hash = trimmed[:i], trimmed[i+1:]
+			}
+		}
+		f.lines = append(f.lines, l)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Read parses the file at path.
+func Read(path string) (*File, error) {
+	r, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+	return Parse(r)
+}
+
+// Entries returns the entries in file order.
+func (f *File) Entries() []Entry {
+	var entries []Entry
+	for i, l := range f.lines {
+		if l.user != "" {
+			entries = append(entries, Entry{User: l.user, Hash: l.hash, Algorithm: Identify(l.hash), Line: i + 1})
+		}
+	}
+	return entries
+}
+
+// Lookup returns user's entry. Apache uses the first of duplicate entries,
+// so Lookup does too.
+func (f *File) Lookup(user string) (Entry, bool) {
+	for _, e := range f.Entries() {
+		if e.User == user {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Insecure returns the entries whose hash is not in a secure format.
+func (f *File) Insecure() []Entry {
+	var insecure []Entry
+	for _, e := range f.Entries() {
+		if !e.Algorithm.Secure() {
+			insecure = append(insecure, e)
+		}
+	}
+	return insecure
+}
+
+// Set replaces user's hash in place, or appends an entry if user has none,
+// and reports whether it appended.
+func (f *File) Set(user, hash string) bool {
+	for i, l := range f.lines {
+		if l.user == user {
+			f.lines[i] = line{text: user + ":" + hash, user: user, hash: hash}
+			return false
+		}
+	}
+	f.lines = append(f.lines, line{text: user + ":" + hash, user: user, hash: hash})
+	return true
+}
+
+// Delete removes every entry for user and reports whether there was one.
+func (f *File) Delete(user string) bool {
+	kept := f.lines[:0]
+	for _, l := range f.lines {
+		if l.user != user {
+			kept = append(kept, l)
+		}
+	}
+	deleted := len(kept) != len(f.lines)
+	f.lines = kept
+	return deleted
+}
+
+// WriteTo writes the file, ending every line with a newline.
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	var n int64
+	for _, l := range f.lines {
+		m, err := io.WriteString(w, l.text+"\n")
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// ValidUser returns an error if user cannot be written to a file.
+func ValidUser(user string) error {
+	switch {
+	case user == "":
+		return fmt.Errorf("the user name is empty")
+	case strings.ContainsAny(user, ":\r\n"):
+		return fmt.Errorf("'%s' is an invalid user name. it may not contain a colon or newline", user)
+	case strings.HasPrefix(user, "#"):
+		return fmt.Errorf("'%s' is an invalid user name. it would be read as a comment", user)
+	case len(user) > 255:
+		return fmt.Errorf("the user name is %d bytes. the limit is 255", len(user))
+	}
+	return nil
+}
+
+// Update reads the file at path, passes it to fn and, if fn succeeds, writes
+// the result back. flag takes os.O_CREATE to create a missing file, with
+// os.O_EXCL to fail if it exists, and os.O_TRUNC to start from an empty file.
+//
+// The file is replaced by renaming a new one over it, keeping its mode and,
+// where permitted, its owner. A reader, such as the web server, sees either
+// the old file or the new one, never a partial write. Concurrent updates are
+// serialized with a lock on the directory holding the file. Locking the file
+// itself would not work across the rename, and a lock file would be left
+// next to files that are often in a served directory.
+func Update(path string, flag int, fn func(*File) error) error {
+	unlock, err := filelock.LockDir(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	fi, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		if flag&os.O_CREATE == 0 {
+			return err
+		}
+		fi = nil
+	case err != nil:
+		return err
+	case flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return fmt.Errorf("%s already exists", path)
+	}
+
+	f := &File{}
+	if fi != nil && flag&os.O_TRUNC == 0 {
+		if f, err = Read(path); err != nil {
+			return err
+		}
+	}
+	if err := fn(f); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	mode := os.FileMode(newFileMode)
+	if fi != nil {
+		mode = fi.Mode().Perm()
+		chown(tmp, fi)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if _, err := f.WriteTo(tmp); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,11 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package htpasswd
+
+import (
+	"os"
+)
+
+// chown is a no-op where os.FileInfo does not expose an owner.
+func chown(*os.File, os.FileInfo) {}
@@ -0,0 +1,18 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package htpasswd
+
+import (
+	"os"
+	"syscall"
+)
+
+// chown gives f the owner and group of the file described by fi. It fails
+// unless running as root or, for the group, as a member of it, in which case
+// the new file keeps the caller's owner as it would with any editor.
+func chown(f *os.File, fi os.FileInfo) {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		_ = f.Chown(int(st.Uid), int(st.Gid))
+	}
+}
@@ -0,0 +1,172 @@
+// Package mac computes and verifies HMACs with the digests supported by
+// str.HMACWithOptions, and loads keys from the places a command line tool
+// can safely take them from.
+//
+// str.HMAC falls back to a key derived from a network interface's hardware
+// address, which anyone on the same network segment can learn. Nothing in
+// this package uses that default: every function requires an explicit,
+// non-empty key.
+package mac
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/schigh/str"
+	"github.com/schigh/tools/pkg/digest"
+)
+
+// DefaultDigest is the digest used when none is given.
+const DefaultDigest = str.HMACDigestTypeSHA256
+
+var errEmptyKey = fmt.Errorf("the HMAC key is empty") //nolint:gochecknoglobals
+
+var digests = map[string]str.HMACDigestType{ //nolint:gochecknoglobals
+	"md5":    str.HMACDigestTypeMD5,
+	"sha1":   str.HMACDigestTypeSHA1,
+	"sha256": str.HMACDigestTypeSHA256,
+	"sha384": str.HMACDigestTypeSHA384,
+	"sha512": str.HMACDigestTypeSHA512,
+}
+
+// ParseDigest converts a digest name (md5, sha1, sha256, sha384 or sha512)
+// into a str.HMACDigestType.
+func ParseDigest(name string) (str.HMACDigestType, error) {
+	d, ok := digests[strings.ToLower(name)]
+	if !ok {
+		return DefaultDigest, fmt.Errorf("'%s' is an invalid digest. use md5, sha1, sha256, sha384 or sha512", name)
+	}
+	return d, nil
+}
+
+// newHash returns the hash constructor str uses for d.
+func newHash(d str.HMACDigestType) (func() hash.Hash, error) {
+	switch d {
+	case str.HMACDigestTypeMD5:
+		return md5.New, nil
+	case str.HMACDigestTypeSHA1:
+		return sha1.New, nil
+	case str.HMACDigestTypeSHA256:
+		return sha256.New, nil
+	case str.HMACDigestTypeSHA384:
+		return sha512.New384, nil
+	case str.HMACDigestTypeSHA512:
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("'%d' is an invalid digest type", int(d))
+	}
+}
+
+// Size returns the size in bytes of a MAC computed with d.
+func Size(d str.HMACDigestType) (int, error) {
+	fn, err := newHash(d)
+	if err != nil {
+		return 0, err
+	}
+	return fn().Size(), nil
+}
+
+// String returns the HMAC of s. It is computed by str.HMACWithOptions.
+func String(s string, key []byte, d str.HMACDigestType) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errEmptyKey
+	}
+	if _, err := newHash(d); err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(str.HMACWithOptions(s, &str.HMACOptions{KeyData: key, DigestType: d}))
+}
+
+// Reader returns the HMAC of everything read from r. Unlike String, which
+// needs the whole message in memory, it streams the input, so it is used for
+// files and standard input. Both produce the same MAC for the same bytes.
+func Reader(r io.Reader, key []byte, d str.HMACDigestType) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errEmptyKey
+	}
+	fn, err := newHash(d)
+	if err != nil {
+		return nil, err
+	}
+	return digest.Reader(func() hash.Hash { return hmac.New(fn, key) }, r)
+}
+
+// File returns the HMAC of the file at path, or of standard input when path
+// is digest.Stdin.
+func File(path string, key []byte, d str.HMACDigestType) ([]byte, error) {
+	if path == digest.Stdin {
+		return Reader(os.Stdin, key, d)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return Reader(f, key, d)
+}
+
+// Verify reports whether mac is the HMAC computed for a message. The
+// comparison takes the same time wherever the MACs differ, so it does not
+// tell an attacker how many leading bytes of a forgery were right.
+func Verify(mac, expected []byte) bool {
+	return hmac.Equal(mac, expected)
+}
+
+// KeyFromFile reads a key from a file. The contents are used exactly as
+// stored, including any trailing newline, so that binary keys survive.
+func KeyFromFile(path string) ([]byte, error) {
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("key file '%s' is empty", path)
+	}
+	return key, nil
+}
+
+// KeyFromEnv reads a key from an environment variable.
+func KeyFromEnv(name string) ([]byte, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok || v == "" {
+		return nil, fmt.Errorf("environment variable '%s' is not set", name)
+	}
+	return []byte(v), nil
+}
+
+// KeyFromHex decodes a hex encoded key.
+func KeyFromHex(s string) ([]byte, error) {
+	key, err := hex.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("the key is not valid hex: %w", err)
+	}
+	if len(key) == 0 {
+		return nil, errEmptyKey
+	}
+	return key, nil
+}
+
+// KeyFromBase64 decodes a standard or URL safe base64 encoded key, with or
+// without padding.
+func KeyFromBase64(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if key, err := enc.DecodeString(s); err == nil {
+			if len(key) == 0 {
+				return nil, errEmptyKey
+			}
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("the key is not valid base64")
+}
@@ -0,0 +1,156 @@
+package mac
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/schigh/str"
+)
+
+// rfc4231 are the HMAC-SHA-256, -384 and -512 test vectors from RFC 4231
+// section 4.
+var rfc4231 = []struct { //nolint:gochecknoglobals
+	name string
+	key  []byte
+	data []byte
+	// truncate is the number of leading bytes of the MAC the RFC gives,
+	// or 0 for all of them
+	truncate int
+	want     map[str.HMACDigestType]string
+}{
+	{
+		name: "test case 1",
+		key:  bytes.Repeat([]byte{0x0b}, 20),
+		data: []byte("Hi There"),
+		want: map[str.HMACDigestType]string{
+			str.HMACDigestTypeSHA256: "b0344c61d8db38535ca8afceaf0bf12b881dc200c9833da726e9376c2e32cff7",
+			str.HMACDigestTypeSHA384: "afd03944d84895626b0825f4ab46907f15f9dadbe4101ec682aa034c7cebc59cfaea9ea9076ede7f4af152e8b2fa9cb6",
+			str.HMACDigestTypeSHA512: "87aa7cdea5ef619d4ff0b4241a1d6cb02379f4e2ce4ec2787ad0b30545e17cdedaa833b7d6b8a702038b274eaea3f4e4be9d914eeb61f1702e696c203a126854",
+		},
+	},
+	{
+		name: "test case 2",
+		key:  []byte("Jefe"),
+		data: []byte("what do ya want for nothing?"),
+		want: map[str.HMACDigestType]string{
+			str.HMACDigestTypeSHA256: "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843",
+			str.HMACDigestTypeSHA384: "af45d2e376484031617f78d2b58a6b1b9c7ef464f5a01b47e42ec3736322445e8e2240ca5e69e2c78b3239ecfab21649",
+			str.HMACDigestTypeSHA512: "164b7a7bfcf819e2e395fbe73b56e0a387bd64222e831fd610270cd7ea2505549758bf75c05a994a6d034f65f8f0e6fdcaeab1a34d4a6b4b636e070a38bce737",
+		},
+	},
+	{
+		name: "test case 3",
+		key:  bytes.Repeat([]byte{0xaa}, 20),
+		data: bytes.Repeat([]byte{0xdd}, 50),
+		want: map[str.HMACDigestType]string{
+			str.HMACDigestTypeSHA256: "773ea91e36800e46854db8ebd09181a72959098b3ef8c122d9635514ced565fe",
+			str.HMACDigestTypeSHA384: "88062608d3e6ad8a0aa2ace014c8a86f0aa635d947ac9febe83ef4e55966144b2a5ab39dc13814b94e3ab6e101a34f27",
+			str.HMACDigestTypeSHA512: "fa73b0089d56a284efb0f0756c890be9b1b5dbdd8ee81a3655f83e33b2279d39bf3e848279a722c806b485a47e67c807b946a337bee8942674278859e13292fb",
+		},
+	},
+	{
+		name: "test case 4",
+		key:  sequence(1, 25),
+		data: bytes.Repeat([]byte{0xcd}, 50),
+		want: map[str.HMACDigestType]string{
+			str.HMACDigestTypeSHA256: "82558a389a443c0ea4cc819899f2083a85f0faa3e578f8077a2e3ff46729665b",
+			str.HMACDigestTypeSHA384: "3e8a69b7783c25851933ab6290af6ca77a9981480850009cc5577c6e1f573b4e6801dd23c4a7d679ccf8a386c674cffb",
+			str.HMACDigestTypeSHA512: "b0ba465637458c6990e5a8c5f61d4af7e576d97ff94b872de76f8050361ee3dba91ca5c11aa25eb4d679275cc5788063a5f19741120c4f2de2adebeb10a298dd",
+		},
+	},
+	{
+		name:     "test case 5",
+		key:      bytes.Repeat([]byte{0x0c}, 20),
+		data:     []byte("Test With Truncation"),
+		truncate: 16,
+		want: map[str.HMACDigestType]string{
+			str.HMACDigestTypeSHA256: "a3b6167473100ee06e0c796c2955552b",
+			str.HMACDigestTypeSHA384: "3abf34c3503b2a23a46efc619baef897",
+			str.HMACDigestTypeSHA512: "415fad6271580a531d4179bc891d87a6",
+		},
+	},
+	{
+		name: "test case 6",
+		key:  bytes.Repeat([]byte{0xaa}, 131),
+		data: []byte("Test Using Larger Than Block-Size Key - Hash Key First"),
+		want: map[str.HMACDigestType]string{
+			str.HMACDigestTypeSHA256: "60e431591ee0b67f0d8a26aacbf5b77f8e0bc6213728c5140546040f0ee37f54",
+			str.HMACDigestTypeSHA384: "4ece084485813e9088d2c63a041bc5b44f9ef1012a2b588f3cd11f05033ac4c60c2ef6ab4030fe8296248df163f44952",
+			str.HMACDigestTypeSHA512: "80b24263c7c1a3ebb71493c1dd7be8b49b46d1f41b4aeec1121b013783f8f3526b56d037e05f2598bd0fd2215d6a1e5295e64f73f63f0aec8b915a985d786598",
+		},
+	},
+	{
+		name: "test case 7",
+		key:  bytes.Repeat([]byte{0xaa}, 131),
+		data: []byte("This is a test using a larger than block-size key and a larger than block-size data. The key needs to be hashed before being used by the HMAC algorithm."),
+		want: map[str.HMACDigestType]string{
+			str.HMACDigestTypeSHA256: "9b09ffa71b942fcb27635fbcd5b0e944bfdc63644f0713938a7f51535c3a35e2",
+			str.HMACDigestTypeSHA384: "6617178e941f020d351e2f254e8fd32c602420feb0b8fb9adccebb82461e99c5a678cc31e799176d3860e6110c46523e",
+			str.HMACDigestTypeSHA512: "e37b6a775dc87dbaa4dfa9f96e5e3ffddebd71f8867289865df5a32d20cdc944b6022cac3c4982b10d5eeb55c3e4de15134676fb6de0446065c97440fa8c6a58",
+		},
+	},
+}
+
+func sequence(from, to byte) []byte {
+	b := make([]byte, 0, int(to-from)+1)
+	for i := from; i <= to; i++ {
+		b = append(b, i)
+	}
+	return b
+}
+
+func truncated(mac []byte, n int) []byte {
+	if n == 0 {
+		return mac
+	}
+	return mac[:n]
+}
+
+func TestRFC4231(t *testing.T) {
+	for _, tc := range rfc4231 {
+		for d, hexWant := range tc.want {
+			want, err := hex.DecodeString(hexWant)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			s, err := String(string(tc.data), tc.key, d)
+			if err != nil {
+				t.Fatalf("%s digest %d: String: %v", tc.name, d, err)
+			}
+			r, err := Reader(bytes.NewReader(tc.data), tc.key, d)
+			if err != nil {
+				t.Fatalf("%s digest %d: Reader: %v", tc.name, d, err)
+			}
+
+			for path, mac := range map[string][]byte{"String": s, "Reader": r} {
+				got := truncated(mac, tc.truncate)
+				if !bytes.Equal(got, want) {
+					t.Errorf("%s digest %d: %s = %x, want %s", tc.name, d, path, got, hexWant)
+				}
+				if !Verify(got, want) {
+					t.Errorf("%s digest %d: Verify rejected the %s MAC", tc.name, d, path)
+				}
+			}
+		}
+	}
+}
+
+func TestVerifyRejectsOneByteChange(t *testing.T) {
+	tc := rfc4231[0]
+	want, err := hex.DecodeString(tc.want[str.HMACDigestTypeSHA256])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range want {
+		forged := append([]byte(nil), want...)
+		forged[i] ^= 0x01
+		if Verify(forged, want) {
+			t.Errorf("Verify accepted a MAC differing in byte %d", i)
+		}
+	}
+	if Verify(want[:len(want)-1], want) {
+		t.Error("Verify accepted a MAC missing its last byte")
+	}
+}
@@ -0,0 +1,195 @@
+// Package nanoid contains a Go implementation of the NanoID project defined at
+// https://github.com/ai/nanoid.
+//
+// Two generation paths are provided. New mirrors the reference nanoid()
+// function: it reads exactly one random byte per character and maps it onto
+// the 64 character URL alphabet. Custom mirrors the reference customAlphabet()
+// function: it uses a bit mask and rejection sampling so that alphabets of any
+// size up to 256 symbols produce unbiased output. Given the same stream of
+// random bytes, both produce the same output as the JavaScript
+// implementation: New reads bytes in order, as nanoid() does from its pool,
+// and Custom walks each batch from its last byte to its first, as
+// customRandom() does.
+package nanoid
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"math/bits"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	// Alphabet is the reference NanoID URL-safe alphabet. The ordering is not
+	// alphabetical on purpose; it matches
+	// https://github.com/ai/nanoid/blob/main/url-alphabet/index.js so that IDs
+	// generated here are byte-for-byte compatible with the reference
+	// implementation.
+	Alphabet = "useandom-26T198340PX75pxJACKVERYMINDBUSHWOLF_GQZbfghjklqvwyzrict"
+	// DefaultSize is the reference ID length. With the default alphabet it
+	// provides roughly 126 bits of entropy, which is comparable to a UUIDv4.
+	DefaultSize = 21
+	// MaxAlphabetSize is the largest alphabet that can be indexed by a single
+	// random byte.
+	MaxAlphabetSize = 256
+	// stepFactor is the reference over-allocation factor used when deciding
+	// how many random bytes to request per batch. It accounts for the bytes
+	// that will be rejected by the mask. See
+	// https://github.com/ai/nanoid/blob/main/index.js#L24.
+	stepFactor = 1.6
+	// urlMask selects the low six bits of a random byte, which is an exact
+	// index into the 64 character default alphabet.
+	urlMask = 63
+)
+
+var (
+	globalLock      = &sync.RWMutex{}                 //nolint:gochecknoglobals
+	globalGenerator = &Generator{Random: rand.Reader} //nolint:gochecknoglobals
+)
+
+// SetGenerator changes the global Generator instance.
+func SetGenerator(g *Generator) {
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	globalGenerator = g
+}
+
+// New generates a NanoID of DefaultSize using the reference alphabet and the
+// global generator.
+func New() (string, error) {
+	return NewWithSize(DefaultSize)
+}
+
+// NewWithSize generates a NanoID of the given size using the reference
+// alphabet and the global generator.
+func NewWithSize(size int) (string, error) {
+	return generator().Generate(size)
+}
+
+// Custom generates an ID of the given size using the characters in alphabet
+// and the global generator.
+func Custom(alphabet string, size int) (string, error) {
+	return generator().Custom(alphabet, size)
+}
+
+func generator() *Generator {
+	globalLock.RLock()
+	defer globalLock.RUnlock()
+	return globalGenerator
+}
+
+// Generator is a producer of NanoID values. Create instances of this struct
+// to inject a specific source of randomness; the package level functions use
+// the instance set with SetGenerator.
+type Generator struct {
+	Random io.Reader
+}
+
+// Generate creates an ID of the given size using the reference alphabet. This
+// follows the reference nanoid() function exactly: one random byte is read
+// per character and masked to an index into the 64 character alphabet. No
+// bytes are ever rejected because the alphabet size is a power of two.
+func (g *Generator) Generate(size int) (string, error) {
+	if size < 1 {
+		return "", fmt.Errorf("size must be positive. got %d", size)
+	}
+
+	b := make([]byte, size)
+	if _, err := io.ReadFull(g.Random, b); err != nil {
+		return "", err
+	}
+
+	out := make([]byte, size)
+	for i := range b {
+		out[i] = Alphabet[b[i]&urlMask]
+	}
+
+	return string(out), nil
+}
+
+// Custom creates an ID of the given size from the symbols in alphabet. The
+// alphabet may contain any Unicode characters, but every character must be
+// unique and there may be at most MaxAlphabetSize of them.
+func (g *Generator) Custom(alphabet string, size int) (string, error) {
+	symbols, err := parseAlphabet(alphabet)
+	if err != nil {
+		return "", err
+	}
+	if size < 1 {
+		return "", fmt.Errorf("size must be positive. got %d", size)
+	}
+
+	out, err := g.read(symbols, size)
+	if err != nil {
+		return "", err
+	}
+
+	return string(out), nil
+}
+
+// read implements the reference customRandom() loop. Random bytes are read
+// in batches of Step(len(symbols), size), each byte is reduced with Mask and
+// any index that falls outside of the alphabet is discarded. Discarding
+// rather than wrapping the index with a modulo is what keeps every symbol
+// equally likely. Each batch is walked backwards, as the reference does with
+// `while (i--)`, so that the same bytes give the same ID.
+func (g *Generator) read(symbols []rune, size int) ([]rune, error) {
+	mask := Mask(len(symbols))
+	buf := make([]byte, Step(len(symbols), size))
+	out := make([]rune, 0, size)
+	for {
+		if _, err := io.ReadFull(g.Random, buf); err != nil {
+			return nil, err
+		}
+		for i := len(buf) - 1; i >= 0; i-- {
+			idx := int(buf[i]) & mask
+			if idx >= len(symbols) {
+				continue
+			}
+			out = append(out, symbols[idx])
+			if len(out) == size {
+				return out, nil
+			}
+		}
+	}
+}
+
+// Mask returns the smallest bit mask of the form 2^n-1 that covers every index
+// into an alphabet of the given length. This matches the reference
+// calculation `(2 << (31 - Math.clz32((alphabet.length - 1) | 1))) - 1`.
+func Mask(alphabetLen int) int {
+	return (2 << (31 - bits.LeadingZeros32(uint32(alphabetLen-1)|1))) - 1
+}
+
+// Step returns the number of random bytes requested per batch when generating
+// an ID of the given size from an alphabet of the given length. This matches
+// the reference `-~(1.6 * mask * size / alphabet.length)`, which truncates
+// and adds one, so it is one more than a ceiling when the product is whole.
+// The batch size decides which bytes are walked together, so it must match
+// for the output to.
+func Step(alphabetLen, size int) int {
+	return int(stepFactor*float64(Mask(alphabetLen))*float64(size)/float64(alphabetLen)) + 1
+}
+
+// parseAlphabet splits alphabet into its symbols and validates that it can be
+// used for unbiased generation.
+func parseAlphabet(alphabet string) ([]rune, error) {
+	if !utf8.ValidString(alphabet) {
+		return nil, fmt.Errorf("alphabet must be valid UTF-8")
+	}
+	symbols := []rune(alphabet)
+	if len(symbols) < 2 || len(symbols) > MaxAlphabetSize {
+		return nil, fmt.Errorf("alphabet must contain between 2 and %d characters. got %d", MaxAlphabetSize, len(symbols))
+	}
+	seen := make(map[rune]struct{}, len(symbols))
+	for _, r := range symbols {
+		if _, ok := seen[r]; ok {
+			return nil, fmt.Errorf("alphabet contains duplicate character %q", r)
+		}
+		seen[r] = struct{}{}
+	}
+
+	return symbols, nil
+}
@@ -0,0 +1,95 @@
+package nanoid
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// stream returns the bytes 0, 7, 14, ... (mod 256), the fixed stream the
+// expected IDs below were produced from by running the reference
+// customRandom() in Node.
+type stream struct{ n int }
+
+func (s *stream) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(s.n * 7)
+		s.n++
+	}
+	return len(p), nil
+}
+
+func TestCustomMatchesReference(t *testing.T) {
+	tests := []struct {
+		alphabet string
+		size     int
+		want     string
+	}{
+		{alphabet: "abc", size: 10, want: "abcabcabca"},
+		{alphabet: Alphabet, size: 21, want: "HMAPTnilZWICX1dcqbONK"},
+		{alphabet: "0123456789", size: 30, want: "813570924681357092468135709246"},
+	}
+	for _, tt := range tests {
+		g := &Generator{Random: &stream{}}
+		got, err := g.Custom(tt.alphabet, tt.size)
+		if err != nil {
+			t.Fatalf("Custom(%q, %d): %v", tt.alphabet, tt.size, err)
+		}
+		if got != tt.want {
+			t.Errorf("Custom(%q, %d) = %q, want %q", tt.alphabet, tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestDistribution(t *testing.T) {
+	// critical values of the chi-square distribution at p = 0.001. the
+	// source is seeded, so the test is deterministic rather than failing
+	// one run in a thousand
+	tests := []struct {
+		name     string
+		alphabet string
+		generate func(g *Generator, size int) (string, error)
+		critical float64
+	}{
+		{
+			name:     "default",
+			alphabet: Alphabet,
+			generate: func(g *Generator, size int) (string, error) { return g.Generate(size) },
+			critical: 103.442, // 63 degrees of freedom
+		},
+		{
+			name:     "three symbols",
+			alphabet: "abc",
+			generate: func(g *Generator, size int) (string, error) { return g.Custom("abc", size) },
+			critical: 13.816, // 2 degrees of freedom
+		},
+	}
+
+	const samples = 300000
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Generator{Random: rand.New(rand.NewSource(1))} //nolint:gosec
+			id, err := tt.generate(g, samples)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			counts := make(map[rune]int, len(tt.alphabet))
+			for _, r := range id {
+				counts[r]++
+			}
+			if len(counts) != len(tt.alphabet) {
+				t.Fatalf("%d of %d symbols were generated", len(counts), len(tt.alphabet))
+			}
+
+			expected := float64(samples) / float64(len(tt.alphabet))
+			chi := 0.0
+			for _, r := range tt.alphabet {
+				d := float64(counts[r]) - expected
+				chi += d * d / expected
+			}
+			if chi > tt.critical {
+				t.Errorf("chi-square = %.3f, over the critical value %.3f", chi, tt.critical)
+			}
+		})
+	}
+}
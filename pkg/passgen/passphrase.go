@@ -0,0 +1,98 @@
+package passgen
+
+import (
+	"bufio"
+	"crypto/rand"
+	_ "embed" // for the word list
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultWords is the number of words in a passphrase. Six words from the
+	// EFF large list provide about 77 bits of entropy.
+	DefaultWords = 6
+	// DefaultSeparator joins passphrase words.
+	DefaultSeparator = "-"
+)
+
+// effLargeWordlist is the EFF large wordlist from
+// https://www.eff.org/files/2016/07/18/eff_large_wordlist.txt, published
+// under CC BY 3.0 US. Each line is a five dice roll and a word, separated by
+// a tab.
+//
+//go:embed eff_large_wordlist.txt
+var effLargeWordlist string
+
+var (
+	wordsOnce sync.Once //nolint:gochecknoglobals
+	words     []string  //nolint:gochecknoglobals
+)
+
+// Wordlist returns the embedded EFF large wordlist in dice roll order.
+func Wordlist() []string {
+	wordsOnce.Do(func() {
+		scanner := bufio.NewScanner(strings.NewReader(effLargeWordlist))
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 2 {
+				words = append(words, fields[1])
+			}
+		}
+	})
+	out := make([]string, len(words))
+	copy(out, words)
+	return out
+}
+
+// PassphrasePolicy describes the passphrases to generate.
+type PassphrasePolicy struct {
+	// Words is the number of words in the passphrase.
+	Words int
+	// Separator joins the words.
+	Separator string
+	// Capitalize uppercases the first letter of each word.
+	Capitalize bool
+}
+
+// DefaultPassphrasePolicy returns a six word, hyphen separated policy.
+func DefaultPassphrasePolicy() PassphrasePolicy {
+	return PassphrasePolicy{
+		Words:     DefaultWords,
+		Separator: DefaultSeparator,
+	}
+}
+
+// Passphrase generates a passphrase by choosing words uniformly at random
+// from the embedded wordlist.
+func Passphrase(p PassphrasePolicy) (string, error) {
+	if p.Words < 1 {
+		return "", fmt.Errorf("'%d' is an invalid word count. it must be at least 1", p.Words)
+	}
+
+	list := Wordlist()
+	max := big.NewInt(int64(len(list)))
+	out := make([]string, p.Words)
+	for i := range out {
+		idx, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		w := list[idx.Int64()]
+		if p.Capitalize {
+			w = strings.ToUpper(w[:1]) + w[1:]
+		}
+		out[i] = w
+	}
+
+	return strings.Join(out, p.Separator), nil
+}
+
+// Entropy returns the entropy, in bits, of a passphrase generated with p. It
+// assumes an attacker knows the wordlist, the separator and the word count.
+func (p PassphrasePolicy) Entropy() float64 {
+	return float64(p.Words) * math.Log2(float64(len(Wordlist())))
+}
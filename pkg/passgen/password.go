@@ -0,0 +1,323 @@
+// Package passgen generates passwords that satisfy a character policy and
+// diceware-style passphrases, using crypto/rand for every random choice.
+package passgen
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/schigh/tools/pkg/randstr"
+)
+
+const (
+	// DefaultSymbols is the set of symbols drawn from when a policy requires
+	// them. It is the printable ASCII punctuation minus quotes, backslash
+	// and backtick, which tend to break shell and config file quoting.
+	DefaultSymbols = "!#$%&()*+,-./:;<=>?@[]^_{|}~"
+	// maxAttempts bounds the retries for NoRepeats. A policy that produces
+	// this many passwords with a repeat in a row is almost certainly
+	// unsatisfiable in practice.
+	maxAttempts = 10000
+)
+
+// Policy describes the passwords to generate. Each character class is only
+// drawn from if its character set is non-empty after Exclude is applied.
+type Policy struct {
+	// Length is the total number of characters.
+	Length int
+	// MinLower, MinUpper, MinDigits and MinSymbols are the minimum number of
+	// characters required from each class.
+	MinLower   int
+	MinUpper   int
+	MinDigits  int
+	MinSymbols int
+	// Symbols is the set of symbol characters. Leave empty to generate
+	// passwords without symbols.
+	Symbols string
+	// Exclude lists characters that must never appear.
+	Exclude string
+	// NoRepeats forbids the same character from appearing twice in a row.
+	NoRepeats bool
+}
+
+// DefaultPolicy returns a 20 character policy that requires at least one
+// character from each class.
+func DefaultPolicy() Policy {
+	return Policy{
+		Length:     20,
+		MinLower:   1,
+		MinUpper:   1,
+		MinDigits:  1,
+		MinSymbols: 1,
+		Symbols:    DefaultSymbols,
+	}
+}
+
+// class is a character set and the minimum number of characters that must be
+// drawn from it.
+type class struct {
+	name  string
+	chars string
+	min   int
+}
+
+// classes returns the policy's non-empty character classes after exclusions.
+// The classes are disjoint: a symbol that is also a letter or digit only
+// counts towards the earlier class.
+func (p Policy) classes() ([]class, error) {
+	all := []class{
+		{name: "lowercase", chars: randstr.Lower, min: p.MinLower},
+		{name: "uppercase", chars: randstr.Upper, min: p.MinUpper},
+		{name: "digit", chars: randstr.Digits, min: p.MinDigits},
+		{name: "symbol", chars: p.Symbols, min: p.MinSymbols},
+	}
+
+	out := make([]class, 0, len(all))
+	required := 0
+	claimed := p.Exclude
+	for _, c := range all {
+		if c.min < 0 {
+			return nil, fmt.Errorf("'%d' is an invalid minimum for %s characters", c.min, c.name)
+		}
+		c.chars = dedupe(randstr.Exclude(c.chars, claimed))
+		claimed += c.chars
+		if c.chars == "" {
+			if c.min > 0 {
+				return nil, fmt.Errorf("policy requires %d %s characters but none are available", c.min, c.name)
+			}
+			continue
+		}
+		required += c.min
+		out = append(out, c)
+	}
+	if p.Length < 1 {
+		return nil, fmt.Errorf("'%d' is an invalid length. it must be at least 1", p.Length)
+	}
+	if required > p.Length {
+		return nil, fmt.Errorf("policy requires %d characters but the length is %d", required, p.Length)
+	}
+
+	return out, nil
+}
+
+// Validate returns an error if the policy cannot be satisfied.
+func (p Policy) Validate() error {
+	_, err := p.classes()
+	return err
+}
+
+// Password generates a password that satisfies p.
+//
+// Every acceptable password is equally likely. The number of characters taken
+// from each class is chosen first, weighted by how many passwords have those
+// counts, as counted by Entropy. Each class then supplies that many uniformly
+// chosen characters and the result is shuffled. With NoRepeats, passwords
+// with a repeat are discarded and the whole process starts again.
+func Password(p Policy) (string, error) {
+	classes, err := p.classes()
+	if err != nil {
+		return "", err
+	}
+	ways := compositions(classes, p.Length)
+
+	for i := 0; i < maxAttempts; i++ {
+		candidate, err := draw(classes, ways, p.Length)
+		if err != nil {
+			return "", err
+		}
+		if !p.NoRepeats || !hasRepeat(candidate) {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("unable to avoid repeated characters after %d attempts. add characters or loosen the minimums", maxAttempts)
+}
+
+// draw generates a password of the given length that meets the class
+// minimums. ways is the table returned by compositions.
+func draw(classes []class, ways [][]*big.Int, length int) (string, error) {
+	out := make([]rune, 0, length)
+	remaining := length
+	for j := len(classes) - 1; j >= 0; j-- {
+		// choose how many of the remaining positions class j takes, in
+		// proportion to the number of strings with that count
+		target, err := rand.Int(rand.Reader, ways[j+1][remaining])
+		if err != nil {
+			return "", err
+		}
+		chars := []rune(classes[j].chars)
+		k := classes[j].min
+		for ; ; k++ {
+			w := term(remaining, k, len(chars), ways[j][remaining-k])
+			if target.Cmp(w) < 0 {
+				break
+			}
+			target.Sub(target, w)
+		}
+		for i := 0; i < k; i++ {
+			r, err := index(len(chars))
+			if err != nil {
+				return "", err
+			}
+			out = append(out, chars[r])
+		}
+		remaining -= k
+	}
+
+	// Fisher-Yates
+	for i := len(out) - 1; i > 0; i-- {
+		j, err := index(i + 1)
+		if err != nil {
+			return "", err
+		}
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out), nil
+}
+
+// index returns a uniform random integer in [0, n).
+func index(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(i.Int64()), nil
+}
+
+// Check returns an error describing the first way in which password fails the
+// policy.
+func (p Policy) Check(password string) error {
+	classes, err := p.classes()
+	if err != nil {
+		return err
+	}
+	if n := len([]rune(password)); n < p.Length {
+		return fmt.Errorf("password has %d characters. the policy requires %d", n, p.Length)
+	}
+	for _, r := range p.Exclude {
+		if strings.ContainsRune(password, r) {
+			return fmt.Errorf("password contains excluded character %q", r)
+		}
+	}
+	for _, c := range classes {
+		if n := count(password, c.chars); n < c.min {
+			return fmt.Errorf("password has %d %s characters. the policy requires %d", n, c.name, c.min)
+		}
+	}
+	if p.NoRepeats && hasRepeat(password) {
+		return fmt.Errorf("password repeats a character")
+	}
+	return nil
+}
+
+// Entropy estimates the entropy, in bits, of a password generated with p. It
+// is the log of the number of strings that meet the class minimums, which is
+// exact when NoRepeats is false. With NoRepeats the count is scaled by the
+// probability that a uniform string has no adjacent repeats, which treats
+// positions as independent and slightly overestimates the result.
+func (p Policy) Entropy() (float64, error) {
+	classes, err := p.classes()
+	if err != nil {
+		return 0, err
+	}
+
+	ways := compositions(classes, p.Length)
+
+	bits := log2(ways[len(classes)][p.Length])
+	if p.NoRepeats && p.Length > 1 {
+		size := 0
+		for _, c := range classes {
+			size += len([]rune(c.chars))
+		}
+		bits += float64(p.Length-1) * math.Log2(float64(size-1)/float64(size))
+	}
+	return bits, nil
+}
+
+// compositions counts the strings that meet the class minimums. ways[j][l] is
+// the number of strings of length l, built from the first j classes, that
+// meet each of those classes' minimums. Adding a class with n characters that
+// contributes k of the positions multiplies the count by C(l+k, k) * n^k.
+func compositions(classes []class, length int) [][]*big.Int {
+	ways := make([][]*big.Int, len(classes)+1)
+	for j := range ways {
+		ways[j] = make([]*big.Int, length+1)
+		for l := range ways[j] {
+			ways[j][l] = big.NewInt(0)
+		}
+	}
+	ways[0][0].SetInt64(1)
+	for j, c := range classes {
+		n := len([]rune(c.chars))
+		for l := 0; l <= length; l++ {
+			if ways[j][l].Sign() == 0 {
+				continue
+			}
+			for k := c.min; l+k <= length; k++ {
+				ways[j+1][l+k].Add(ways[j+1][l+k], term(l+k, k, n, ways[j][l]))
+			}
+		}
+	}
+	return ways
+}
+
+// term returns C(l, k) * n^k * rest, the number of strings of length l in
+// which a class of n characters takes k positions and the other classes
+// arrange themselves in one of rest ways over the remainder.
+func term(l, k, n int, rest *big.Int) *big.Int {
+	t := new(big.Int).Binomial(int64(l), int64(k))
+	t.Mul(t, new(big.Int).Exp(big.NewInt(int64(n)), big.NewInt(int64(k)), nil))
+	return t.Mul(t, rest)
+}
+
+// log2 returns the base 2 logarithm of a positive big integer.
+func log2(v *big.Int) float64 {
+	if v.Sign() <= 0 {
+		return math.Inf(-1)
+	}
+	f, _ := new(big.Float).SetInt(v).Float64()
+	if !math.IsInf(f, 0) {
+		return math.Log2(f)
+	}
+	// too large for a float64; shift down and add the shift back
+	shift := v.BitLen() - 64
+	f, _ = new(big.Float).SetInt(new(big.Int).Rsh(v, uint(shift))).Float64()
+	return math.Log2(f) + float64(shift)
+}
+
+func count(s, chars string) int {
+	n := 0
+	for _, r := range s {
+		if strings.ContainsRune(chars, r) {
+			n++
+		}
+	}
+	return n
+}
+
+func hasRepeat(s string) bool {
+	var prev rune = -1
+	for _, r := range s {
+		if r == prev {
+			return true
+		}
+		prev = r
+	}
+	return false
+}
+
+func dedupe(s string) string {
+	seen := map[rune]struct{}{}
+	sb := strings.Builder{}
+	for _, r := range s {
+		if _, ok := seen[r]; ok {
+			continue
+		}
+		seen[r] = struct{}{}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,68 @@
+package passgen
+
+import (
+	"testing"
+
+	"github.com/schigh/tools/pkg/randstr"
+)
+
+func TestCompositionsMatchBruteForce(t *testing.T) {
+	// leave a, b, C, 1, 2 and ! so every string can be enumerated. 'a' is
+	// also a symbol, and only counts as lowercase
+	p := Policy{
+		Length:     5,
+		MinLower:   1,
+		MinUpper:   1,
+		MinDigits:  2,
+		MinSymbols: 1,
+		Symbols:    "a!",
+		Exclude:    randstr.Exclude(randstr.Lower+randstr.Upper+randstr.Digits, "abC12"),
+	}
+	classes, err := p.classes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alphabet := []rune("abC12!")
+	want := 0
+	s := make([]rune, p.Length)
+	var walk func(i int)
+	walk = func(i int) {
+		if i == len(s) {
+			if p.Check(string(s)) == nil {
+				want++
+			}
+			return
+		}
+		for _, r := range alphabet {
+			s[i] = r
+			walk(i + 1)
+		}
+	}
+	walk(0)
+
+	got := compositions(classes, p.Length)[len(classes)][p.Length]
+	if !got.IsInt64() || got.Int64() != int64(want) {
+		t.Errorf("compositions counted %s strings, brute force found %d", got, want)
+	}
+}
+
+func TestPasswordMeetsRarePolicies(t *testing.T) {
+	policies := []Policy{
+		{Length: 8, MinLower: 1, MinUpper: 1, MinDigits: 5, MinSymbols: 1, Symbols: DefaultSymbols},
+		{Length: 12, MinDigits: 12},
+		{Length: 4, MinSymbols: 2, Symbols: "!"},
+		{Length: 16, MinLower: 4, MinUpper: 4, MinDigits: 4, MinSymbols: 4, Symbols: DefaultSymbols, NoRepeats: true},
+	}
+	for _, p := range policies {
+		for i := 0; i < 200; i++ {
+			pw, err := Password(p)
+			if err != nil {
+				t.Fatalf("%+v: %v", p, err)
+			}
+			if err := p.Check(pw); err != nil {
+				t.Fatalf("%+v: %q: %v", p, pw, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package randstr
+
+import (
+	"fmt"
+	"math"
+	"unicode/utf8"
+)
+
+// BitsPerChar returns the entropy contributed by each character drawn
+// uniformly from an alphabet of the given size.
+func BitsPerChar(alphabetSize int) float64 {
+	return math.Log2(float64(alphabetSize))
+}
+
+// Entropy returns the total entropy, in bits, of a string of the given length
+// drawn uniformly from alphabet.
+func Entropy(alphabet string, length int) float64 {
+	return float64(length) * BitsPerChar(utf8.RuneCountInString(alphabet))
+}
+
+// LengthForBits returns the shortest length that provides at least bits of
+// entropy when drawing from alphabet.
+func LengthForBits(alphabet string, bits float64) (int, error) {
+	size := utf8.RuneCountInString(alphabet)
+	if size < 2 {
+		return 0, fmt.Errorf("alphabet must contain at least 2 characters. got %d", size)
+	}
+	if bits <= 0 || math.IsNaN(bits) || math.IsInf(bits, 0) {
+		return 0, fmt.Errorf("'%g' is an invalid number of bits. it must be positive", bits)
+	}
+	return int(math.Ceil(bits / BitsPerChar(size))), nil
+}
+
+// BitsForCollision returns the entropy needed so that generating count
+// strings has at most probability p of producing any duplicate.
+//
+// This inverts the birthday bound p = 1 - exp(-k(k-1) / 2N), where k is count
+// and N is the number of possible strings, giving N = k(k-1) / -2ln(1-p).
+func BitsForCollision(count, p float64) (float64, error) {
+	if count < 2 || math.IsInf(count, 0) || math.IsNaN(count) {
+		return 0, fmt.Errorf("'%g' is an invalid count. it must be at least 2", count)
+	}
+	if p <= 0 || p >= 1 || math.IsNaN(p) {
+		return 0, fmt.Errorf("'%g' is an invalid collision probability. it must be between 0 and 1", p)
+	}
+	pairs := count * (count - 1) / 2
+	return math.Log2(pairs) - math.Log2(-math.Log1p(-p)), nil
+}
+
+// LengthForCollision returns the shortest length for which generating count
+// strings from alphabet has at most probability p of producing a duplicate.
+func LengthForCollision(alphabet string, count, p float64) (int, error) {
+	bits, err := BitsForCollision(count, p)
+	if err != nil {
+		return 0, err
+	}
+	return LengthForBits(alphabet, bits)
+}
+
+// CollisionProbability returns the probability that at least two of count
+// strings of the given length drawn from alphabet are equal. The calculation
+// is done in log space so that it stays accurate for very large alphabets and
+// lengths, where the number of possible strings overflows a float64.
+func CollisionProbability(alphabet string, length int, count float64) float64 {
+	if count < 2 {
+		return 0
+	}
+	pairs := count * (count - 1) / 2
+	exponent := math.Log(pairs) - Entropy(alphabet, length)*math.Ln2
+	return -math.Expm1(-math.Exp(exponent))
+}
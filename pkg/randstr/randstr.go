@@ -0,0 +1,124 @@
+// Package randstr generates uniformly distributed random strings from named or
+// custom alphabets using crypto/rand.
+//
+// Each character is chosen with the mask and rejection sampling technique
+// from package nanoid, so every symbol in the alphabet is equally likely
+// regardless of the alphabet's size. Mapping random bytes onto an alphabet
+// with a modulo, as in `charset[b%len(charset)]`, favours the first
+// 256%len(charset) symbols and is what this package replaces.
+package randstr
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/schigh/tools/pkg/nanoid"
+)
+
+// Named alphabets.
+const (
+	// Alnum is ASCII letters of both cases and digits.
+	Alnum = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	// Lower is lowercase ASCII letters.
+	Lower = "abcdefghijklmnopqrstuvwxyz"
+	// Upper is uppercase ASCII letters.
+	Upper = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	// Hex is lowercase hexadecimal digits.
+	Hex = "0123456789abcdef"
+	// Crockford32 is Douglas Crockford's base32 alphabet, which omits I, L, O
+	// and U. See https://www.crockford.com/base32.html.
+	Crockford32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	// Base58 is the Bitcoin base58 alphabet, which omits 0, O, I and l.
+	Base58 = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	// URLSafe is the RFC 4648 base64url alphabet.
+	URLSafe = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+	// Digits is the decimal digits.
+	Digits = "0123456789"
+
+	// Lookalikes are characters that are commonly confused with one another
+	// when read by a person.
+	Lookalikes = "01IOlo"
+)
+
+var (
+	alphabets = map[string]string{ //nolint:gochecknoglobals
+		"alnum":       Alnum,
+		"lower":       Lower,
+		"upper":       Upper,
+		"hex":         Hex,
+		"crockford32": Crockford32,
+		"base58":      Base58,
+		"urlsafe":     URLSafe,
+		"digits":      Digits,
+	}
+
+	globalLock      = &sync.RWMutex{}                 //nolint:gochecknoglobals
+	globalGenerator = &Generator{Random: rand.Reader} //nolint:gochecknoglobals
+)
+
+// Alphabet returns the named alphabet.
+func Alphabet(name string) (string, error) {
+	a, ok := alphabets[strings.ToLower(name)]
+	if !ok {
+		return "", fmt.Errorf("'%s' is not a known alphabet. use one of: %s", name, strings.Join(Names(), ", "))
+	}
+	return a, nil
+}
+
+// Names returns the names accepted by Alphabet in sorted order.
+func Names() []string {
+	out := make([]string, 0, len(alphabets))
+	for name := range alphabets {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ExcludeLookalikes returns alphabet without any of the characters in
+// Lookalikes.
+func ExcludeLookalikes(alphabet string) string {
+	return Exclude(alphabet, Lookalikes)
+}
+
+// Exclude returns alphabet without any of the characters in chars.
+func Exclude(alphabet, chars string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(chars, r) {
+			return -1
+		}
+		return r
+	}, alphabet)
+}
+
+// SetGenerator changes the global Generator instance.
+func SetGenerator(g *Generator) {
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	globalGenerator = g
+}
+
+// String generates a random string of the given length from alphabet using
+// the global generator.
+func String(alphabet string, length int) (string, error) {
+	globalLock.RLock()
+	g := globalGenerator
+	globalLock.RUnlock()
+	return g.String(alphabet, length)
+}
+
+// Generator produces random strings from an injectable source of randomness.
+type Generator struct {
+	Random io.Reader
+}
+
+// String generates a random string of the given length from alphabet. The
+// alphabet must contain between 2 and 256 unique characters.
+func (g *Generator) String(alphabet string, length int) (string, error) {
+	n := nanoid.Generator{Random: g.Random}
+	return n.Custom(alphabet, length)
+}
@@ -0,0 +1,165 @@
+// Package secret reads passwords and other secrets for command line tools
+// without putting them in shell history or the process list.
+//
+// A secret comes from exactly one source: a file, an environment variable,
+// standard input when it is piped, or, when standard input is a terminal, a
+// prompt that does not echo what is typed.
+package secret
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// ErrMismatch is returned when a confirmed prompt's two entries differ.
+var ErrMismatch = errors.New("the secrets do not match") //nolint:gochecknoglobals
+
+// Options select where a secret is read from. At most one of Value, File and
+// Env may be set; with none, standard input is used.
+type Options struct {
+	// Value is a secret given directly, such as with a -secret flag. It is
+	// only kept for compatibility, since it is visible to other users.
+	Value string
+	// File is a file holding the secret.
+	File string
+	// Env is an environment variable holding the secret.
+	Env string
+	// Prompt is written to standard error before reading from a terminal.
+	Prompt string
+	// Confirm asks for the secret twice on a terminal and fails with
+	// ErrMismatch if the entries differ.
+	Confirm bool
+}
+
+// Read returns the secret selected by opts.
+//
+// A file or piped standard input may end with a newline, which is removed
+// since nearly every way of writing one adds it; a secret that must end in a
+// newline can come from Env. Only the first line of piped input is used, so
+// a secret cannot contain a newline unless it comes from File or Env.
+// Nothing else, including leading or trailing spaces, is altered.
+func Read(opts Options) ([]byte, error) {
+	given := 0
+	for _, v := range []string{opts.Value, opts.File, opts.Env} {
+		if v != "" {
+			given++
+		}
+	}
+	if given > 1 {
+		return nil, fmt.Errorf("only one secret source may be given")
+	}
+
+	var (
+		s   []byte
+		err error
+	)
+	switch {
+	case opts.Value != "":
+		s = []byte(opts.Value)
+	case opts.File != "":
+		s, err = fromFile(opts.File)
+	case opts.Env != "":
+		s, err = fromEnv(opts.Env)
+	case isTerminal(os.Stdin):
+		s, err = fromPrompt(os.Stdin, opts.Prompt, opts.Confirm)
+	default:
+		s, err = firstLine(os.Stdin)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(s) == 0 {
+		return nil, fmt.Errorf("the secret is empty")
+	}
+	return s, nil
+}
+
+func fromFile(path string) ([]byte, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return trimNewline(b), nil
+}
+
+func fromEnv(name string) ([]byte, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return nil, fmt.Errorf("environment variable '%s' is not set", name)
+	}
+	return []byte(v), nil
+}
+
+// firstLine reads up to the first newline of r.
+func firstLine(r io.Reader) ([]byte, error) {
+	line, err := bufio.NewReader(r).ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return trimNewline(line), nil
+}
+
+func fromPrompt(tty *os.File, prompt string, confirm bool) ([]byte, error) {
+	if prompt == "" {
+		prompt = "Secret: "
+	}
+	first, err := prompted(tty, prompt)
+	if err != nil || !confirm {
+		return first, err
+	}
+	second, err := prompted(tty, "Confirm "+strings.ToLower(prompt[:1])+prompt[1:])
+	if err != nil {
+		return nil, err
+	}
+	if string(first) != string(second) {
+		return nil, ErrMismatch
+	}
+	return first, nil
+}
+
+func prompted(tty *os.File, prompt string) ([]byte, error) {
+	_, _ = fmt.Fprint(os.Stderr, prompt)
+	s, err := readNoEcho(tty)
+	// the newline the user typed was not echoed either
+	_, _ = fmt.Fprintln(os.Stderr)
+	return s, err
+}
+
+// readLine reads one byte at a time up to a newline, so that nothing past
+// the line is consumed from a terminal that is read again.
+func readLine(r io.Reader) ([]byte, error) {
+	var (
+		line []byte
+		b    [1]byte
+	)
+	for {
+		n, err := r.Read(b[:])
+		if n == 1 {
+			if b[0] == '\n' {
+				return trimNewline(line), nil
+			}
+			line = append(line, b[0])
+		}
+		if err == io.EOF {
+			return trimNewline(line), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func trimNewline(b []byte) []byte {
+	if n := len(b); n > 0 && b[n-1] == '\n' {
+		b = b[:n-1]
+	}
+	if n := len(b); n > 0 && b[n-1] == '\r' {
+		b = b[:n-1]
+	}
+	return b
+}
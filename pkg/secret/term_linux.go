@@ -0,0 +1,72 @@
+//go:build linux
+// +build linux
+
+package secret
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+func getTermios(fd uintptr) (syscall.Termios, error) {
+	var t syscall.Termios
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCGETS, uintptr(unsafe.Pointer(&t))); errno != 0 {
+		return t, errno
+	}
+	return t, nil
+}
+
+func setTermios(fd uintptr, t syscall.Termios) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCSETS, uintptr(unsafe.Pointer(&t))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// isTerminal reports whether f is a terminal. Asking for its termios settings
+// is the same test isatty(3) uses, and unlike checking for a character
+// device it is not fooled by /dev/null.
+func isTerminal(f *os.File) bool {
+	_, err := getTermios(f.Fd())
+	return err == nil
+}
+
+// readNoEcho reads a line from the terminal with echo turned off. The
+// terminal is restored before returning, and also if the process is
+// interrupted while waiting, so that Ctrl-C does not leave the shell
+// without echo.
+func readNoEcho(tty *os.File) ([]byte, error) {
+	fd := tty.Fd()
+	old, err := getTermios(fd)
+	if err != nil {
+		return nil, err
+	}
+	quiet := old
+	quiet.Lflag &^= syscall.ECHO
+	quiet.Lflag |= syscall.ICANON | syscall.ISIG
+	if err := setTermios(fd, quiet); err != nil {
+		return nil, err
+	}
+
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		select {
+		case sig := <-signals:
+			_ = setTermios(fd, old)
+			_, _ = os.Stderr.WriteString("\n")
+			os.Exit(128 + int(sig.(syscall.Signal)))
+		case <-done:
+		}
+	}()
+	defer func() {
+		signal.Stop(signals)
+		close(done)
+		_ = setTermios(fd, old)
+	}()
+
+	return readLine(tty)
+}
@@ -0,0 +1,25 @@
+//go:build !linux
+// +build !linux
+
+package secret
+
+import (
+	"fmt"
+	"os"
+)
+
+// isTerminal reports whether f looks like a terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// readNoEcho is only implemented on Linux, where echo is turned off with
+// termios ioctls. Elsewhere the secret has to come from a pipe, a file or
+// the environment rather than be shown on screen as it is typed.
+func readNoEcho(*os.File) ([]byte, error) {
+	return nil, fmt.Errorf("prompting without echo is not supported on this platform. pipe the secret to standard input or use a secret file or environment variable")
+}
@@ -0,0 +1,214 @@
+// Package slugify turns arbitrary Unicode text, such as an article title, into
+// a URL-safe slug.
+//
+// Text is lowercased, transliterated to ASCII and split into words on any
+// character that is not an ASCII letter or digit. The words are then joined
+// with a separator:
+//
+//	slugify.Make("Crème Brûlée: A Ωmega Guide") // creme-brulee-a-omega-guide
+//
+// Transliteration happens in three steps. Characters with an entry in the
+// built-in table are replaced first, which covers letters such as ß, æ and
+// the Cyrillic alphabet whose decompositions would lose information. The
+// remaining text is NFKD normalized and combining marks are dropped, which
+// reduces accented Latin and Greek letters to their base letter. Finally the
+// table is applied again to catch base letters, such as Greek, that only
+// appear after decomposition.
+package slugify
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultSeparator joins the words of a slug.
+const DefaultSeparator = "-"
+
+// Options configure a Slugifier.
+type Options struct {
+	// Separator joins words. DefaultSeparator is used when empty. It must be
+	// URL-safe: ASCII letters, digits, '-', '_', '.' or '~'.
+	Separator string
+	// MaxLength limits the slug length in bytes. Slugs are truncated at a
+	// word boundary; a single word longer than MaxLength is cut. Zero means
+	// no limit. Otherwise it must leave room for a letter, the separator and
+	// a one digit suffix, so that Unique has something to work with.
+	MaxLength int
+	// Stopwords are removed from the slug, unless doing so would leave it
+	// empty. Matching is case-insensitive.
+	Stopwords []string
+	// Replacements are applied before the built-in transliteration table
+	// and take precedence over it. Keys are single characters; values may be
+	// empty to drop the character.
+	Replacements map[rune]string
+}
+
+// Slugifier converts text into slugs for a fixed configuration. It is safe
+// for concurrent use.
+type Slugifier struct {
+	separator    string
+	maxLength    int
+	stopwords    map[string]struct{}
+	replacements map[rune]string
+}
+
+var defaultSlugifier = &Slugifier{separator: DefaultSeparator} //nolint:gochecknoglobals
+
+// Make converts text into a slug using the default options.
+func Make(text string) string {
+	return defaultSlugifier.Slug(text)
+}
+
+// New creates a Slugifier from the given options.
+func New(opts Options) (*Slugifier, error) {
+	sep := opts.Separator
+	if sep == "" {
+		sep = DefaultSeparator
+	}
+	for _, r := range sep {
+		if !isWordRune(r) && !strings.ContainsRune("-_.~", r) {
+			return nil, fmt.Errorf("'%s' is an invalid separator. it must only contain URL-safe characters", sep)
+		}
+	}
+	if opts.MaxLength < 0 {
+		return nil, fmt.Errorf("'%d' is an invalid max length. it must not be negative", opts.MaxLength)
+	}
+	if min := len(sep) + 2; opts.MaxLength > 0 && opts.MaxLength < min {
+		return nil, fmt.Errorf("'%d' is an invalid max length. it must be at least %d to hold a suffixed slug", opts.MaxLength, min)
+	}
+
+	s := &Slugifier{
+		separator:    sep,
+		maxLength:    opts.MaxLength,
+		replacements: opts.Replacements,
+	}
+	if len(opts.Stopwords) > 0 {
+		s.stopwords = make(map[string]struct{}, len(opts.Stopwords))
+		for _, w := range opts.Stopwords {
+			s.stopwords[strings.ToLower(w)] = struct{}{}
+		}
+	}
+
+	return s, nil
+}
+
+// Slug converts text into a slug. The result is empty if text contains no
+// characters that can be transliterated to ASCII letters or digits.
+func (s *Slugifier) Slug(text string) string {
+	return s.join(s.words(text), s.maxLength)
+}
+
+// Unique converts text into a slug that is not in taken. If the plain slug is
+// already taken, a numeric suffix is appended, starting at 2, and the slug is
+// shortened as needed to keep the suffixed result within MaxLength. Once the
+// suffix leaves no room for the slug, the suffix is used alone. An error is
+// returned if every slug that fits within MaxLength is taken. The caller is
+// responsible for adding the result to taken.
+func (s *Slugifier) Unique(text string, taken map[string]bool) (string, error) {
+	words := s.words(text)
+	slug := s.join(words, s.maxLength)
+	if !taken[slug] {
+		return slug, nil
+	}
+
+	for n := 2; ; n++ {
+		suffix := strconv.Itoa(n)
+		base := ""
+		switch limit := s.maxLength - len(s.separator) - len(suffix); {
+		case s.maxLength == 0:
+			base = s.join(words, 0)
+		case len(suffix) > s.maxLength:
+			return "", fmt.Errorf("every slug for '%s' within %d bytes is taken", text, s.maxLength)
+		case limit > 0:
+			base = s.join(words, limit)
+		}
+		candidate := suffix
+		if base != "" {
+			candidate = base + s.separator + suffix
+		}
+		if !taken[candidate] {
+			return candidate, nil
+		}
+	}
+}
+
+// words transliterates text and splits it into lowercase ASCII words, with
+// stopwords removed.
+func (s *Slugifier) words(text string) []string {
+	text = s.transliterate(strings.ToLower(text))
+
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !isWordRune(r)
+	})
+	if len(s.stopwords) == 0 {
+		return words
+	}
+
+	kept := make([]string, 0, len(words))
+	for _, w := range words {
+		if _, ok := s.stopwords[w]; !ok {
+			kept = append(kept, w)
+		}
+	}
+	if len(kept) == 0 {
+		return words
+	}
+	return kept
+}
+
+func (s *Slugifier) transliterate(text string) string {
+	text = s.replace(text)
+	decomposed := norm.NFKD.String(text)
+	text = strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		return r
+	}, decomposed)
+	return strings.ToLower(s.replace(text))
+}
+
+// replace applies the caller's replacements and the built-in table.
+func (s *Slugifier) replace(text string) string {
+	sb := strings.Builder{}
+	sb.Grow(len(text))
+	for _, r := range text {
+		if v, ok := s.replacements[r]; ok {
+			sb.WriteString(v)
+			continue
+		}
+		if v, ok := table[r]; ok {
+			sb.WriteString(v)
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// join joins words with the separator, dropping trailing words until the
+// result fits in limit bytes. If even the first word does not fit, it is cut
+// to limit. A limit of zero means no limit.
+func (s *Slugifier) join(words []string, limit int) string {
+	slug := strings.Join(words, s.separator)
+	if limit == 0 || len(slug) <= limit {
+		return slug
+	}
+
+	for n := len(words) - 1; n > 0; n-- {
+		slug = strings.Join(words[:n], s.separator)
+		if len(slug) <= limit {
+			return slug
+		}
+	}
+	return words[0][:limit]
+}
+
+// isWordRune reports whether r may appear inside a slug word.
+func isWordRune(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
@@ -0,0 +1,10 @@
+package slugify
+
+// EnglishStopwords are short, common English words that rarely help identify
+// a page. Pass them as Options.Stopwords to drop them from slugs.
+var EnglishStopwords = []string{ //nolint:gochecknoglobals
+	"a", "an", "and", "are", "as", "at", "be", "but", "by", "for", "from",
+	"how", "in", "into", "is", "it", "of", "on", "or", "so", "than", "that",
+	"the", "this", "to", "was", "what", "when", "where", "which", "who",
+	"why", "will", "with",
+}
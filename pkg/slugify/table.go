@@ -0,0 +1,88 @@
+package slugify
+
+// table maps lowercase characters to their ASCII transliteration. It only
+// needs entries for characters that NFKD normalization does not reduce to an
+// ASCII letter on its own.
+var table = map[rune]string{ //nolint:gochecknoglobals
+	// Latin
+	'ß': "ss",
+	'æ': "ae",
+	'œ': "oe",
+	'ø': "o",
+	'đ': "d",
+	'ð': "d",
+	'þ': "th",
+	'ł': "l",
+	'ı': "i",
+	'ħ': "h",
+	'ŋ': "ng",
+	'ĸ': "k",
+
+	// Greek. Accented forms decompose to these under NFKD.
+	'α': "a",
+	'β': "v",
+	'γ': "g",
+	'δ': "d",
+	'ε': "e",
+	'ζ': "z",
+	'η': "i",
+	'θ': "th",
+	'ι': "i",
+	'κ': "k",
+	'λ': "l",
+	'μ': "m",
+	'ν': "n",
+	'ξ': "x",
+	'ο': "o",
+	'π': "p",
+	'ρ': "r",
+	'σ': "s",
+	'ς': "s",
+	'τ': "t",
+	'υ': "y",
+	'φ': "f",
+	'χ': "ch",
+	'ψ': "ps",
+	'ω': "o",
+
+	// Cyrillic, including the Ukrainian and Belarusian letters. ё and й are
+	// listed explicitly because NFKD would reduce them to е and и.
+	'а': "a",
+	'б': "b",
+	'в': "v",
+	'г': "g",
+	'ґ': "g",
+	'д': "d",
+	'е': "e",
+	'ё': "yo",
+	'є': "ye",
+	'ж': "zh",
+	'з': "z",
+	'и': "i",
+	'і': "i",
+	'ї': "yi",
+	'й': "y",
+	'к': "k",
+	'л': "l",
+	'м': "m",
+	'н': "n",
+	'о': "o",
+	'п': "p",
+	'р': "r",
+	'с': "s",
+	'т': "t",
+	'у': "u",
+	'ў': "u",
+	'ф': "f",
+	'х': "kh",
+	'ц': "ts",
+	'ч': "ch",
+	'ш': "sh",
+	'щ': "shch",
+	'ъ': "",
+	'ы': "y",
+	'ь': "",
+	'э': "e",
+	'ю': "yu",
+	'я': "ya",
+}
@@ -0,0 +1,48 @@
+package sqids
+
+// DefaultBlocklist is the list of words that New filters out of generated IDs
+// when Options.Blocklist is nil. It is a deliberately short list of common
+// English profanity, and it is not compatible with other Sqids
+// implementations: they default to the much longer, multilingual list from
+// https://github.com/sqids/sqids-blocklist, so the same numbers can encode to
+// different IDs. Configure that list explicitly when encoded IDs must match
+// another implementation character for character. Decoding is never affected
+// by the blocklist.
+var DefaultBlocklist = []string{ //nolint:gochecknoglobals
+	"anal",
+	"anus",
+	"arse",
+	"ass",
+	"bastard",
+	"bitch",
+	"boob",
+	"cock",
+	"crap",
+	"cum",
+	"cunt",
+	"dick",
+	"dildo",
+	"dyke",
+	"fag",
+	"fuck",
+	"jizz",
+	"kike",
+	"nazi",
+	"nigga",
+	"nigger",
+	"penis",
+	"piss",
+	"porn",
+	"pussy",
+	"rape",
+	"retard",
+	"sex",
+	"shit",
+	"slut",
+	"spic",
+	"tits",
+	"twat",
+	"vagina",
+	"wank",
+	"whore",
+}
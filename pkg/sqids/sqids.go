@@ -0,0 +1,297 @@
+// Package sqids contains a Go implementation of the Sqids specification
+// defined at https://github.com/sqids/sqids-spec.
+//
+// Sqids encode one or more non-negative integers into short, URL-safe strings
+// and decode them back. They are meant to keep sequential database IDs out of
+// URLs, not to protect them: anyone who knows the alphabet can decode an ID.
+//
+// IDs produced here are decodable by every other Sqids implementation that is
+// configured with the same alphabet. Encoding produces identical output only
+// when the minimum length and blocklist match as well, and DefaultBlocklist is
+// NOT the specification's default list: with the default options, IDs that
+// happen to contain a word on one list but not the other encode differently
+// here than in other implementations. To match them, load the list from
+// https://github.com/sqids/sqids-blocklist into Options.Blocklist.
+package sqids
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	// DefaultAlphabet is the alphabet used by the reference implementations.
+	DefaultAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	// MinAlphabetLength is the smallest alphabet the algorithm supports. One
+	// character is reserved as the prefix and one as the separator, so anything
+	// smaller could not encode a digit.
+	MinAlphabetLength = 3
+	// MaxMinLength is the largest minimum length that may be requested.
+	MaxMinLength = 255
+)
+
+// Options configure an Encoder.
+type Options struct {
+	// Alphabet is the set of characters that IDs are built from. It must be
+	// at least MinAlphabetLength single-byte characters with no duplicates.
+	// DefaultAlphabet is used when empty.
+	Alphabet string
+	// MinLength pads IDs to at least this many characters.
+	MinLength int
+	// Blocklist contains words that must never appear in an ID. DefaultBlocklist
+	// is used when nil; pass an empty, non-nil slice to disable the check.
+	Blocklist []string
+}
+
+// Encoder encodes and decodes Sqids for a fixed configuration. It is safe for
+// concurrent use.
+type Encoder struct {
+	alphabet  string
+	minLength int
+	blocklist []string
+}
+
+// New creates an Encoder from the given options.
+func New(opts Options) (*Encoder, error) {
+	alphabet := opts.Alphabet
+	if alphabet == "" {
+		alphabet = DefaultAlphabet
+	}
+	if len(alphabet) < MinAlphabetLength {
+		return nil, fmt.Errorf("alphabet must contain at least %d characters. got %d", MinAlphabetLength, len(alphabet))
+	}
+	seen := make(map[byte]struct{}, len(alphabet))
+	for i := 0; i < len(alphabet); i++ {
+		c := alphabet[i]
+		if c >= 0x80 {
+			return nil, fmt.Errorf("alphabet cannot contain multibyte characters")
+		}
+		if _, ok := seen[c]; ok {
+			return nil, fmt.Errorf("alphabet contains duplicate character %q", c)
+		}
+		seen[c] = struct{}{}
+	}
+	if opts.MinLength < 0 || opts.MinLength > MaxMinLength {
+		return nil, fmt.Errorf("minimum length must be between 0 and %d. got %d", MaxMinLength, opts.MinLength)
+	}
+
+	blocklist := opts.Blocklist
+	if blocklist == nil {
+		blocklist = DefaultBlocklist
+	}
+
+	return &Encoder{
+		alphabet:  shuffle(alphabet),
+		minLength: opts.MinLength,
+		blocklist: filterBlocklist(alphabet, blocklist),
+	}, nil
+}
+
+// Encode converts numbers into a single ID. An empty slice encodes to the
+// empty string.
+func (e *Encoder) Encode(numbers []uint64) (string, error) {
+	if len(numbers) == 0 {
+		return "", nil
+	}
+	return e.encode(numbers, 0)
+}
+
+// Decode converts an ID back into the numbers it encodes. Following the
+// specification, an ID that is not valid for this alphabet decodes to an
+// empty slice rather than an error. Values that would overflow a uint64 are
+// treated the same way.
+//
+// Several IDs can decode to the same numbers, for example when padding was
+// added by a different minimum length. Callers that need a single canonical
+// URL should re-encode the result and compare it to the input.
+func (e *Encoder) Decode(id string) []uint64 {
+	if id == "" {
+		return []uint64{}
+	}
+	for i := 0; i < len(id); i++ {
+		if strings.IndexByte(e.alphabet, id[i]) == -1 {
+			return []uint64{}
+		}
+	}
+
+	offset := strings.IndexByte(e.alphabet, id[0])
+	alphabet := reverse(e.alphabet[offset:] + e.alphabet[:offset])
+
+	out := make([]uint64, 0, 1)
+	rest := id[1:]
+	for rest != "" {
+		separator := alphabet[0]
+		chunk := rest
+		idx := strings.IndexByte(rest, separator)
+		if idx != -1 {
+			chunk = rest[:idx]
+		}
+		if chunk == "" {
+			// everything after a blank chunk is padding
+			return out
+		}
+
+		n, ok := toNumber(chunk, alphabet[1:])
+		if !ok {
+			return []uint64{}
+		}
+		out = append(out, n)
+
+		if idx == -1 {
+			break
+		}
+		alphabet = shuffle(alphabet)
+		rest = rest[idx+1:]
+	}
+
+	return out
+}
+
+// encode implements the reference encodeNumbers() function. When the result
+// contains a blocked word it is regenerated with a higher increment, which
+// shifts the starting offset into the alphabet.
+func (e *Encoder) encode(numbers []uint64, increment int) (string, error) {
+	n := len(e.alphabet)
+	if increment > n {
+		return "", fmt.Errorf("reached max attempts to re-generate the ID")
+	}
+
+	offset := uint64(len(numbers))
+	for i, v := range numbers {
+		offset += uint64(e.alphabet[v%uint64(n)]) + uint64(i)
+	}
+	start := int((offset%uint64(n) + uint64(increment)) % uint64(n))
+
+	alphabet := e.alphabet[start:] + e.alphabet[:start]
+	prefix := alphabet[0]
+	alphabet = reverse(alphabet)
+
+	sb := strings.Builder{}
+	sb.WriteByte(prefix)
+	for i, v := range numbers {
+		sb.WriteString(toID(v, alphabet[1:]))
+		if i < len(numbers)-1 {
+			sb.WriteByte(alphabet[0])
+			alphabet = shuffle(alphabet)
+		}
+	}
+
+	if e.minLength > sb.Len() {
+		sb.WriteByte(alphabet[0])
+		for e.minLength-sb.Len() > 0 {
+			alphabet = shuffle(alphabet)
+			take := int(math.Min(float64(e.minLength-sb.Len()), float64(len(alphabet))))
+			sb.WriteString(alphabet[:take])
+		}
+	}
+
+	id := sb.String()
+	if e.isBlocked(id) {
+		return e.encode(numbers, increment+1)
+	}
+
+	return id, nil
+}
+
+// isBlocked reports whether id contains a blocklisted word. Short IDs and
+// short words must match exactly, and words containing digits only match at
+// the start or end of the ID, which is how the reference implementation
+// avoids rejecting too many IDs built from leetspeak-looking fragments.
+func (e *Encoder) isBlocked(id string) bool {
+	id = strings.ToLower(id)
+	for _, word := range e.blocklist {
+		if len(word) > len(id) {
+			continue
+		}
+		switch {
+		case len(id) <= 3 || len(word) <= 3:
+			if id == word {
+				return true
+			}
+		case strings.ContainsAny(word, "0123456789"):
+			if strings.HasPrefix(id, word) || strings.HasSuffix(id, word) {
+				return true
+			}
+		case strings.Contains(id, word):
+			return true
+		}
+	}
+	return false
+}
+
+// shuffle is the deterministic, consistent shuffle from the specification.
+// It is its own kind of hash of the alphabet: the same input always yields
+// the same output, on every implementation.
+func shuffle(alphabet string) string {
+	chars := []byte(alphabet)
+	n := len(chars)
+	for i, j := 0, n-1; j > 0; i, j = i+1, j-1 {
+		r := (i*j + int(chars[i]) + int(chars[j])) % n
+		chars[i], chars[r] = chars[r], chars[i]
+	}
+	return string(chars)
+}
+
+// toID writes num in the positional base defined by alphabet.
+func toID(num uint64, alphabet string) string {
+	base := uint64(len(alphabet))
+	var buf [64]byte
+	i := len(buf)
+	for {
+		i--
+		buf[i] = alphabet[num%base]
+		num /= base
+		if num == 0 {
+			break
+		}
+	}
+	return string(buf[i:])
+}
+
+// toNumber is the inverse of toID. It reports false if the value does not
+// fit in a uint64.
+func toNumber(id string, alphabet string) (uint64, bool) {
+	base := uint64(len(alphabet))
+	var num uint64
+	for i := 0; i < len(id); i++ {
+		digit := uint64(strings.IndexByte(alphabet, id[i]))
+		if num > (math.MaxUint64-digit)/base {
+			return 0, false
+		}
+		num = num*base + digit
+	}
+	return num, true
+}
+
+func reverse(s string) string {
+	b := []byte(s)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
+
+// filterBlocklist lowercases the blocklist and drops words that are too short
+// to matter or that contain characters the alphabet can never produce.
+func filterBlocklist(alphabet string, blocklist []string) []string {
+	lower := strings.ToLower(alphabet)
+	out := make([]string, 0, len(blocklist))
+	for _, word := range blocklist {
+		if len(word) < 3 {
+			continue
+		}
+		word = strings.ToLower(word)
+		ok := true
+		for i := 0; i < len(word); i++ {
+			if strings.IndexByte(lower, word[i]) == -1 {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			out = append(out, word)
+		}
+	}
+	return out
+}
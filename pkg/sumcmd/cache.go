@@ -0,0 +1,68 @@
+package sumcmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/schigh/tools/pkg/hashcache"
+)
+
+// cacheEnv enables the cache without -cache when set to a non-empty value
+// other than "0". Setting it to a path also moves the database there.
+const cacheEnv = "HASH_CACHE"
+
+// cacheOptions are the flags that control the hash cache.
+type cacheOptions struct {
+	enabled bool
+	disable bool
+	verify  bool
+}
+
+func (o *cacheOptions) register(fs *flag.FlagSet) {
+	fs.BoolVar(&o.enabled, "cache", false, "reuse digests of unchanged files from the on-disk cache (also enabled by "+cacheEnv+"=1)")
+	fs.BoolVar(&o.disable, "no-cache", false, "neither read nor write the cache, even if "+cacheEnv+" is set")
+	fs.BoolVar(&o.verify, "verify-cache", false, "hash every file and report cache entries that were wrong. implies -cache")
+}
+
+// open returns the cache selected by the flags and environment, or nil when
+// caching is off. A cache that cannot be opened is reported and skipped
+// rather than failing the command, since it only affects speed.
+func (o *cacheOptions) open(name string) *hashcache.Cache {
+	env := os.Getenv(cacheEnv)
+	if o.disable || !(o.enabled || o.verify || (env != "" && env != "0")) {
+		return nil
+	}
+
+	path := env
+	if path == "" || path == "0" || path == "1" {
+		var err error
+		if path, err = hashcache.DefaultPath(); err != nil {
+			fail(name, fmt.Errorf("cache disabled: %w", err))
+			return nil
+		}
+	}
+	c, err := hashcache.Open(path)
+	if err != nil {
+		fail(name, fmt.Errorf("cache disabled: %w", err))
+		return nil
+	}
+	c.Verify = o.verify
+	return c
+}
+
+// closeCache saves the cache and, with -verify-cache, reports the files whose
+// cached digests were wrong. It returns 1 if there were any.
+func closeCache(name string, c *hashcache.Cache) int {
+	if err := c.Save(); err != nil {
+		fail(name, fmt.Errorf("unable to save the cache: %w", err))
+	}
+	bad := c.Mismatched()
+	for _, f := range bad {
+		_, _ = fmt.Fprintf(os.Stderr, "%s: %s: cached digest was wrong and has been replaced\n", name, f)
+	}
+	if len(bad) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,251 @@
+package sumcmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/schigh/tools/pkg/digest"
+	"github.com/schigh/tools/pkg/hashcache"
+	"github.com/schigh/tools/pkg/hashes"
+)
+
+// checkOptions are the flags that only apply to -c.
+type checkOptions struct {
+	ignoreMissing bool
+	quiet         bool
+	strict        bool
+	warn          bool
+	cache         *hashcache.Cache
+}
+
+// status is the outcome of verifying one entry.
+type status int
+
+const (
+	statusOK status = iota
+	statusFailed
+	statusMissing
+	statusUnreadable
+)
+
+// result is the outcome of verifying one entry, in the order it was listed.
+type result struct {
+	entry  digest.Entry
+	status status
+	err    error
+}
+
+// resolver picks the algorithm for a checksum file entry and decodes its
+// digest.
+type resolver func(e digest.Entry) (hashes.Algorithm, []byte, error)
+
+// gnuDefaults are the algorithms a bare GNU-style digest may be, tried in
+// order, when the algorithm was not given. Only the SHA-2 family and its
+// predecessors are listed since they are what *sum files are written with;
+// other algorithms need -a. Their digest sizes all differ, so at most one of
+// them decodes a given hex digest.
+var gnuDefaults = []string{"md5", "sha1", "sha224", "sha256", "sha384", "sha512"} //nolint:gochecknoglobals
+
+// newResolver returns the resolver for a command. A fixed algorithm, from a
+// single algorithm tool or an explicit -a, is used for GNU-style lines and
+// BSD-style lines must name it. Otherwise BSD-style lines use their tag and
+// GNU-style lines use the first of gnuDefaults that can decode the digest.
+func newResolver(fixed *hashes.Algorithm) resolver {
+	return func(e digest.Entry) (hashes.Algorithm, []byte, error) {
+		if e.Tag != "" {
+			a, err := hashes.LookupTag(e.Tag)
+			if err != nil {
+				return hashes.Algorithm{}, nil, err
+			}
+			if fixed != nil && a.Name != fixed.Name {
+				return hashes.Algorithm{}, nil, fmt.Errorf("'%s' is not a %s checksum", e.Tag, fixed.Tag)
+			}
+			sum, err := digest.Decode(e.Digest, a.Name, a.Size())
+			return a, sum, err
+		}
+		if fixed != nil {
+			sum, err := digest.Decode(e.Digest, fixed.Name, fixed.Size())
+			return *fixed, sum, err
+		}
+		for _, name := range gnuDefaults {
+			a, err := hashes.Lookup(name)
+			if err != nil {
+				return hashes.Algorithm{}, nil, err
+			}
+			if sum, err := digest.Decode(e.Digest, a.Name, a.Size()); err == nil {
+				return a, sum, nil
+			}
+		}
+		return hashes.Algorithm{}, nil, fmt.Errorf("'%s' is not a digest of a known size. use -a", e.Digest)
+	}
+}
+
+// check verifies every checksum file listed in files and returns a
+// coreutils-compatible exit code: 0 when every listed file matched, 1 when a
+// file failed, could not be read or no line could be parsed. Lines that
+// cannot be parsed are only an error with --strict.
+func check(name string, files []string, resolve resolver, opts checkOptions) int {
+	if len(files) == 0 {
+		files = []string{digest.Stdin}
+	}
+
+	code := 0
+	for _, f := range files {
+		if c := checkFile(name, f, resolve, opts); c != 0 {
+			code = c
+		}
+	}
+	return code
+}
+
+func checkFile(name, file string, resolve resolver, opts checkOptions) int {
+	label := file
+	if file == digest.Stdin {
+		label = "standard input"
+	}
+
+	var r io.Reader = os.Stdin
+	if file != digest.Stdin {
+		f, err := os.Open(file)
+		if err != nil {
+			return fail(name, err)
+		}
+		defer func() { _ = f.Close() }()
+		r = f
+	}
+
+	entries, malformed, err := digest.ParseSums(r)
+	if err != nil {
+		return fail(name, fmt.Errorf("%s: %w", label, err))
+	}
+
+	// entries whose algorithm or digest cannot be resolved are malformed too
+	algs := make([]hashes.Algorithm, 0, len(entries))
+	sums := make([][]byte, 0, len(entries))
+	valid := entries[:0]
+	for _, e := range entries {
+		a, sum, err := resolve(e)
+		if err != nil {
+			malformed = append(malformed, &digest.ParseError{Line: e.Line})
+			continue
+		}
+		algs = append(algs, a)
+		sums = append(sums, sum)
+		valid = append(valid, e)
+	}
+	if opts.warn {
+		for _, m := range malformed {
+			_, _ = fmt.Fprintf(os.Stderr, "%s: %s: %d: improperly formatted checksum line\n", name, label, m.Line)
+		}
+	}
+	if len(valid) == 0 {
+		_, _ = fmt.Fprintf(os.Stderr, "%s: %s: no properly formatted checksum lines found\n", name, label)
+		return 1
+	}
+
+	results := verify(valid, algs, sums, opts.cache)
+
+	var failed, unreadable, verified int
+	for _, res := range results {
+		n := res.entry.Name
+		switch res.status {
+		case statusOK:
+			verified++
+			if !opts.quiet {
+				fmt.Println(n + ": OK")
+			}
+		case statusFailed:
+			verified++
+			failed++
+			fmt.Println(n + ": FAILED")
+		case statusMissing:
+			if opts.ignoreMissing {
+				continue
+			}
+			unreadable++
+			fail(name, res.err)
+			fmt.Println(n + ": MISSING")
+		case statusUnreadable:
+			unreadable++
+			fail(name, res.err)
+			fmt.Println(n + ": FAILED open or read")
+		}
+	}
+
+	code := 0
+	if len(malformed) > 0 {
+		warnf(name, len(malformed), "line is improperly formatted", "lines are improperly formatted")
+		if opts.strict {
+			code = 1
+		}
+	}
+	if unreadable > 0 {
+		warnf(name, unreadable, "listed file could not be read", "listed files could not be read")
+		code = 1
+	}
+	if failed > 0 {
+		warnf(name, failed, "computed checksum did NOT match", "computed checksums did NOT match")
+		code = 1
+	}
+	if opts.ignoreMissing && verified == 0 {
+		_, _ = fmt.Fprintf(os.Stderr, "%s: %s: no file was verified\n", name, label)
+		code = 1
+	}
+	return code
+}
+
+// verify hashes the listed files with a worker per CPU and returns the
+// results in the order they were listed. A file listed more than once is
+// hashed each time, as coreutils does.
+func verify(entries []digest.Entry, algs []hashes.Algorithm, sums [][]byte, cache *hashcache.Cache) []result {
+	results := make([]result, len(entries))
+	jobs := make(chan int)
+
+	workers := runtime.NumCPU()
+	if workers > len(entries) {
+		workers = len(entries)
+	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = verifyEntry(entries[i], algs[i], sums[i], cache)
+			}
+		}()
+	}
+	for i := range entries {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+func verifyEntry(e digest.Entry, a hashes.Algorithm, want []byte, cache *hashcache.Cache) result {
+	sum, err := cache.File(a.Name, a.New, e.Name)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return result{entry: e, status: statusMissing, err: err}
+	case err != nil:
+		return result{entry: e, status: statusUnreadable, err: err}
+	case !digest.Equal(sum, want):
+		return result{entry: e, status: statusFailed}
+	default:
+		return result{entry: e, status: statusOK}
+	}
+}
+
+func warnf(name string, n int, singular, plural string) {
+	msg := plural
+	if n == 1 {
+		msg = singular
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "%s: WARNING: %d %s\n", name, n, msg)
+}
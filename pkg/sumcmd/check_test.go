@@ -0,0 +1,217 @@
+package sumcmd
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/schigh/tools/pkg/hashes"
+)
+
+const (
+	helloMD5    = "b1946ac92492d2347c6235b4d2611184"
+	helloSHA256 = "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+	wrongSHA256 = "0000000000000000000000000000000000000000000000000000000000000000"
+)
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	hello := filepath.Join(dir, "hello.txt")
+	if err := ioutil.WriteFile(hello, []byte("hello\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.txt")
+
+	tests := []struct {
+		name       string
+		lines      []string
+		fixed      string
+		opts       checkOptions
+		wantCode   int
+		wantStdout []string
+		wantStderr []string
+		notStdout  []string
+	}{
+		{
+			name: "gnu, binary and bsd lines match",
+			lines: []string{
+				helloSHA256 + "  " + hello,
+				helloMD5 + " *" + hello,
+				"SHA256 (" + hello + ") = " + helloSHA256,
+			},
+			wantStdout: []string{hello + ": OK\n" + hello + ": OK\n" + hello + ": OK\n"},
+		},
+		{
+			name:      "quiet hides OK",
+			lines:     []string{helloSHA256 + "  " + hello},
+			opts:      checkOptions{quiet: true},
+			notStdout: []string{"OK"},
+		},
+		{
+			name:       "mismatch",
+			lines:      []string{wrongSHA256 + "  " + hello},
+			wantCode:   1,
+			wantStdout: []string{hello + ": FAILED"},
+			wantStderr: []string{"WARNING: 1 computed checksum did NOT match"},
+		},
+		{
+			name:       "missing file",
+			lines:      []string{helloSHA256 + "  " + missing},
+			wantCode:   1,
+			wantStdout: []string{missing + ": MISSING"},
+			wantStderr: []string{"WARNING: 1 listed file could not be read"},
+		},
+		{
+			name:       "missing file ignored",
+			lines:      []string{helloSHA256 + "  " + missing, helloSHA256 + "  " + hello},
+			opts:       checkOptions{ignoreMissing: true},
+			wantStdout: []string{hello + ": OK"},
+			notStdout:  []string{"MISSING"},
+		},
+		{
+			name:       "nothing verified when every file is missing",
+			lines:      []string{helloSHA256 + "  " + missing},
+			opts:       checkOptions{ignoreMissing: true},
+			wantCode:   1,
+			wantStderr: []string{"no file was verified"},
+		},
+		{
+			name:       "blank lines are skipped",
+			lines:      []string{"", helloSHA256 + "  " + hello, "   ", ""},
+			wantStdout: []string{hello + ": OK"},
+		},
+		{
+			name:       "malformed lines are counted",
+			lines:      []string{helloSHA256 + "  " + hello, "garbage", "more garbage"},
+			wantStdout: []string{hello + ": OK"},
+			wantStderr: []string{"WARNING: 2 lines are improperly formatted"},
+		},
+		{
+			name:       "malformed lines are warned about with -w",
+			lines:      []string{helloSHA256 + "  " + hello, "garbage"},
+			opts:       checkOptions{warn: true},
+			wantStderr: []string{": 2: improperly formatted checksum line", "WARNING: 1 line is improperly formatted"},
+		},
+		{
+			name:       "malformed lines fail with -strict",
+			lines:      []string{helloSHA256 + "  " + hello, "garbage"},
+			opts:       checkOptions{strict: true},
+			wantCode:   1,
+			wantStdout: []string{hello + ": OK"},
+		},
+		{
+			name:       "no well formed lines",
+			lines:      []string{"garbage"},
+			wantCode:   1,
+			wantStderr: []string{"no properly formatted checksum lines found"},
+		},
+		{
+			name:       "bsd tag for another algorithm is malformed",
+			lines:      []string{"MD5 (" + hello + ") = " + helloMD5, helloSHA256 + "  " + hello},
+			fixed:      "sha256",
+			opts:       checkOptions{warn: true, strict: true},
+			wantCode:   1,
+			wantStdout: []string{hello + ": OK"},
+			wantStderr: []string{": 1: improperly formatted checksum line"},
+		},
+		{
+			name:       "gnu digest of the wrong size for the algorithm is malformed",
+			lines:      []string{helloMD5 + "  " + hello},
+			fixed:      "sha256",
+			wantCode:   1,
+			wantStderr: []string{"no properly formatted checksum lines found"},
+		},
+		{
+			name: "missing, mismatched and malformed together",
+			lines: []string{
+				helloSHA256 + "  " + missing,
+				wrongSHA256 + "  " + hello,
+				"garbage",
+				helloSHA256 + "  " + hello,
+			},
+			wantCode:   1,
+			wantStdout: []string{missing + ": MISSING", hello + ": FAILED", hello + ": OK"},
+			wantStderr: []string{
+				"WARNING: 1 line is improperly formatted",
+				"WARNING: 1 listed file could not be read",
+				"WARNING: 1 computed checksum did NOT match",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sums := filepath.Join(t.TempDir(), "SUMS")
+			if err := ioutil.WriteFile(sums, []byte(strings.Join(tt.lines, "\n")+"\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			var fixed *hashes.Algorithm
+			if tt.fixed != "" {
+				a, err := hashes.Lookup(tt.fixed)
+				if err != nil {
+					t.Fatal(err)
+				}
+				fixed = &a
+			}
+
+			var code int
+			stdout, stderr := capture(t, func() {
+				code = check("hash", []string{sums}, newResolver(fixed), tt.opts)
+			})
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d\nstdout:\n%s\nstderr:\n%s", code, tt.wantCode, stdout, stderr)
+			}
+			for _, s := range tt.wantStdout {
+				if !strings.Contains(stdout, s) {
+					t.Errorf("stdout does not contain %q:\n%s", s, stdout)
+				}
+			}
+			for _, s := range tt.notStdout {
+				if strings.Contains(stdout, s) {
+					t.Errorf("stdout contains %q:\n%s", s, stdout)
+				}
+			}
+			for _, s := range tt.wantStderr {
+				if !strings.Contains(stderr, s) {
+					t.Errorf("stderr does not contain %q:\n%s", s, stderr)
+				}
+			}
+		})
+	}
+}
+
+// capture runs fn with os.Stdout and os.Stderr redirected and returns what
+// was written to each.
+func capture(t *testing.T, fn func()) (string, string) {
+	t.Helper()
+
+	read := func(target **os.File) func() string {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		orig := *target
+		*target = w
+		done := make(chan string)
+		go func() {
+			var b bytes.Buffer
+			_, _ = io.Copy(&b, r)
+			done <- b.String()
+		}()
+		return func() string {
+			*target = orig
+			_ = w.Close()
+			return <-done
+		}
+	}
+
+	stdout := read(&os.Stdout)
+	stderr := read(&os.Stderr)
+	fn()
+	return stdout(), stderr()
+}
@@ -0,0 +1,91 @@
+package sumcmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/schigh/tools/pkg/hashid"
+)
+
+// identified is the JSON output for one input.
+type identified struct {
+	Input      string             `json:"input"`
+	Candidates []hashid.Candidate `json:"candidates"`
+}
+
+// runIdentify implements "hash identify". It ranks the candidate formats of
+// each argument, or of each line of standard input when there are none, and
+// exits 1 if any input matched nothing.
+func runIdentify(name string, args []string) int {
+	var (
+		asJSON bool
+		limit  int
+	)
+
+	name += " identify"
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.BoolVar(&asJSON, "json", false, "print JSON")
+	fs.IntVar(&limit, "n", 0, "show at most this many candidates per input (0 shows all)")
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "usage: %s [-json] [-n count] [string]...\n", name)
+		_, _ = fmt.Fprintln(fs.Output(), "with no arguments, each line of standard input is identified")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	inputs := fs.Args()
+	if len(inputs) == 0 {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				inputs = append(inputs, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return fail(name, err)
+		}
+	}
+
+	code := 0
+	results := make([]identified, len(inputs))
+	for i, in := range inputs {
+		candidates := hashid.Identify(in)
+		if len(candidates) == 0 {
+			code = 1
+		}
+		if limit > 0 && len(candidates) > limit {
+			candidates = candidates[:limit]
+		}
+		results[i] = identified{Input: in, Candidates: candidates}
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return fail(name, err)
+		}
+		return code
+	}
+
+	for i, r := range results {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Println(r.Input)
+		if len(r.Candidates) == 0 {
+			fmt.Println("  no known format")
+			continue
+		}
+		for _, c := range r.Candidates {
+			fmt.Printf("  %3d  %s: %s\n", c.Score, c.Format, strings.Join(c.Reasons, "; "))
+		}
+	}
+	return code
+}
@@ -0,0 +1,327 @@
+// Package sumcmd implements the command line interface shared by the hash,
+// md5, sha1 and sha256 tools. It behaves like the coreutils *sum tools: each
+// file argument is hashed and printed as "<digest>  <name>", "-" reads
+// standard input, and a failure to read one file is reported without
+// stopping the others. With -c the arguments are checksum files in GNU or
+// BSD format instead, and every file they list is verified. The hash command
+// also has a tree subcommand that hashes a whole directory, see runTree, and
+// an identify subcommand that guesses the format of a hash, see runIdentify.
+package sumcmd
+
+import (
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/schigh/tools/pkg/digest"
+	"github.com/schigh/tools/pkg/hashes"
+)
+
+const (
+	// randomSize is the number of random bytes hashed when there is nothing
+	// else to hash.
+	randomSize = 1024
+	// defaultAlgorithm is used by the hash command when -a is omitted.
+	defaultAlgorithm = "sha256"
+	// sriAlgorithm is used by -sri when -a is omitted, as recommended by the
+	// Subresource Integrity specification.
+	sriAlgorithm = "sha384"
+	// allAlgorithms selects every registered algorithm.
+	allAlgorithms = "all"
+)
+
+// Main runs the command and exits the process. If algorithm is empty the
+// command accepts -a to choose one or more algorithms; otherwise it always
+// uses the given algorithm.
+func Main(name, algorithm string) {
+	os.Exit(Run(name, algorithm, os.Args[1:]))
+}
+
+// Run runs the command with the given arguments and returns the exit code.
+func Run(name, algorithm string, args []string) int {
+	if algorithm == "" && len(args) > 0 {
+		switch args[0] {
+		case "tree":
+			return runTree(name, args[1:])
+		case "identify":
+			return runIdentify(name, args[1:])
+		}
+	}
+
+	var (
+		literal stringFlag
+		algs    = algorithm
+		checkIt bool
+		opts    checkOptions
+		encName string
+		sri     bool
+		expect  string
+		caching cacheOptions
+	)
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Var(&literal, "s", "hash this string instead of files")
+	fs.BoolVar(&checkIt, "c", false, "read checksums from the files and verify them")
+	fs.BoolVar(&opts.ignoreMissing, "ignore-missing", false, "with -c, skip files that do not exist")
+	fs.BoolVar(&opts.quiet, "quiet", false, "with -c, do not print OK for each verified file")
+	fs.BoolVar(&opts.strict, "strict", false, "with -c, exit non-zero for improperly formatted checksum lines")
+	fs.BoolVar(&opts.warn, "w", false, "with -c, warn about each improperly formatted checksum line")
+	fs.StringVar(&encName, "encoding", string(digest.Hex), "digest encoding ("+strings.Join(digest.Encodings(), "|")+")")
+	fs.BoolVar(&sri, "sri", false, "print one subresource integrity value per file, using sha384 unless -a is given")
+	fs.StringVar(&expect, "verify", "", "compare each input against this digest, in any encoding, and print OK or FAILED")
+	caching.register(fs)
+	if algorithm == "" {
+		fs.StringVar(&algs, "a", defaultAlgorithm, "comma separated algorithms, or 'all' ("+strings.Join(hashes.Names(), "|")+")")
+	}
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "usage: %s [flags] [file|glob|-]...\n", name)
+		_, _ = fmt.Fprintf(fs.Output(), "       %s -c [-ignore-missing] [-quiet] [-strict] [-w] [sums|-]...\n", name)
+		if algorithm == "" {
+			_, _ = fmt.Fprintf(fs.Output(), "       %s tree [flags] [dir]\n", name)
+			_, _ = fmt.Fprintf(fs.Output(), "       %s identify [-json] [string]...\n", name)
+		}
+		_, _ = fmt.Fprintln(fs.Output(), "with no arguments, standard input is hashed when it is not a terminal; otherwise random bytes are hashed")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if sri && algorithm == "" && !flagSet(fs, "a") {
+		algs = sriAlgorithm
+	}
+	selected, err := selectAlgorithms(algs)
+	if err != nil {
+		return fail(name, err)
+	}
+	enc, err := digest.ParseEncoding(encName)
+	if err != nil {
+		return fail(name, err)
+	}
+	if sri {
+		enc = digest.SRI
+	}
+	if enc == digest.SRI {
+		for _, a := range selected {
+			if !digest.SupportsSRI(a.Name) {
+				return fail(name, fmt.Errorf("%s cannot be used for subresource integrity. use sha256, sha384 or sha512", a.Name))
+			}
+		}
+	}
+	p := printer{algs: selected, encoding: enc, integrity: sri}
+	if expect != "" {
+		if len(selected) != 1 {
+			return fail(name, fmt.Errorf("-verify accepts a single algorithm"))
+		}
+		want, err := digest.Decode(expect, selected[0].Name, selected[0].Size())
+		if err != nil {
+			return fail(name, err)
+		}
+		p.expect = want
+	}
+
+	if checkIt {
+		var fixed *hashes.Algorithm
+		if algorithm != "" || flagSet(fs, "a") {
+			if len(selected) != 1 {
+				return fail(name, fmt.Errorf("-c accepts a single algorithm"))
+			}
+			fixed = &selected[0]
+		}
+		opts.cache = caching.open(name)
+		code := check(name, fs.Args(), newResolver(fixed), opts)
+		if c := closeCache(name, opts.cache); c != 0 {
+			code = c
+		}
+		return code
+	}
+	constructors := make([]func() hash.Hash, len(selected))
+	for i, a := range selected {
+		constructors[i] = a.New
+	}
+
+	if literal.set {
+		return hashReader(name, p, constructors, strings.NewReader(literal.value), "")
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		if isTerminal(os.Stdin) && p.expect == nil {
+			return random(name, p, constructors)
+		}
+		files = []string{digest.Stdin}
+	}
+
+	files, err = digest.Expand(files)
+	if err != nil {
+		return fail(name, err)
+	}
+
+	names := make([]string, len(selected))
+	for i, a := range selected {
+		names[i] = a.Name
+	}
+	cache := caching.open(name)
+
+	code := 0
+	for _, f := range files {
+		sums, err := cache.MultiFile(names, constructors, f)
+		if err != nil {
+			code = fail(name, err)
+			continue
+		}
+		if c := p.print(name, sums, f); c != 0 {
+			code = c
+		}
+	}
+	if c := closeCache(name, cache); c != 0 {
+		code = c
+	}
+
+	return code
+}
+
+// selectAlgorithms resolves a comma separated list of algorithm names.
+func selectAlgorithms(list string) ([]hashes.Algorithm, error) {
+	if strings.TrimSpace(list) == allAlgorithms {
+		return hashes.All(), nil
+	}
+
+	var out []hashes.Algorithm
+	for _, name := range strings.Split(list, ",") {
+		a, err := hashes.Lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+// printer writes digests in the chosen encoding, or with -verify compares
+// them against the expected digest.
+type printer struct {
+	algs      []hashes.Algorithm
+	encoding  digest.Encoding
+	integrity bool
+	expect    []byte
+}
+
+// print writes the digests of one input and returns the exit code for it.
+//
+// A single algorithm prints a coreutils line and several print one BSD
+// tagged line each, since bare digests of different algorithms could not be
+// told apart. With -sri the digests are joined into one integrity value, as
+// in an HTML integrity attribute. An empty name prints bare digests, and the
+// raw encoding prints nothing but the digest bytes.
+func (p printer) print(cmd string, sums [][]byte, name string) int {
+	if p.expect != nil {
+		status := "OK"
+		code := 0
+		if !digest.Equal(sums[0], p.expect) {
+			status = "FAILED"
+			code = 1
+		}
+		if name != "" {
+			status = name + ": " + status
+		}
+		fmt.Println(status)
+		return code
+	}
+
+	encoded := make([]string, len(sums))
+	for i, a := range p.algs {
+		s, err := digest.Encode(p.encoding, a.Name, sums[i])
+		if err != nil {
+			return fail(cmd, err)
+		}
+		encoded[i] = s
+	}
+
+	switch {
+	case p.encoding == digest.Raw:
+		fmt.Print(strings.Join(encoded, ""))
+	case p.integrity && name == "":
+		fmt.Println(strings.Join(encoded, " "))
+	case p.integrity:
+		fmt.Println(digest.Line(strings.Join(encoded, " "), name))
+	default:
+		for i, a := range p.algs {
+			switch {
+			case name == "" && len(p.algs) == 1:
+				fmt.Println(encoded[i])
+			case name == "":
+				fmt.Printf("%s = %s\n", a.Tag, encoded[i])
+			case len(p.algs) == 1:
+				fmt.Println(digest.Line(encoded[i], name))
+			default:
+				fmt.Println(digest.TagLine(a.Tag, encoded[i], name))
+			}
+		}
+	}
+	return 0
+}
+
+func hashReader(name string, p printer, constructors []func() hash.Hash, r io.Reader, label string) int {
+	sums, err := digest.Multi(constructors, r)
+	if err != nil {
+		return fail(name, err)
+	}
+	return p.print(name, sums, label)
+}
+
+// random hashes random bytes, which is how the single algorithm tools
+// behaved before they accepted files. It is kept for running a tool with no
+// input.
+func random(name string, p printer, constructors []func() hash.Hash) int {
+	b := make([]byte, randomSize)
+	if _, err := rand.Read(b); err != nil {
+		return fail(name, err)
+	}
+	return hashReader(name, p, constructors, strings.NewReader(string(b)), "")
+}
+
+// flagSet reports whether the named flag was given on the command line.
+func flagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+func fail(name string, err error) int {
+	_, _ = fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+	return 1
+}
+
+// stringFlag records whether -s was given so that -s "" hashes the empty
+// string rather than falling through to file mode.
+type stringFlag struct {
+	value string
+	set   bool
+}
+
+func (s *stringFlag) String() string {
+	return s.value
+}
+
+func (s *stringFlag) Set(v string) error {
+	s.value = v
+	s.set = true
+	return nil
+}
@@ -0,0 +1,163 @@
+package sumcmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/schigh/tools/pkg/hashes"
+	"github.com/schigh/tools/pkg/hashtree"
+)
+
+// runTree implements "hash tree". It prints the manifest of a directory as
+// JSON, or with -diff compares a directory or a second manifest against a
+// saved one and exits 1 if anything changed.
+func runTree(name string, args []string) int {
+	var (
+		alg      string
+		ignore   listFlag
+		symlinks string
+		workers  int
+		out      string
+		rootOnly bool
+		against  string
+		caching  cacheOptions
+	)
+
+	name += " tree"
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&alg, "a", defaultAlgorithm, "algorithm ("+strings.Join(hashes.Names(), "|")+")")
+	fs.Var(&ignore, "ignore", "skip paths or names matching this pattern. may be repeated")
+	fs.StringVar(&symlinks, "symlinks", "record", "how to handle symlinks (record|follow|skip)")
+	fs.IntVar(&workers, "workers", 0, "maximum files hashed at once (default number of CPUs)")
+	fs.StringVar(&out, "o", "", "write the manifest to this file instead of standard output")
+	fs.BoolVar(&rootOnly, "root", false, "print only the Merkle root")
+	caching.register(fs)
+	fs.StringVar(&against, "diff", "", "compare against this manifest and report added, removed and changed files")
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "usage: %s [flags] [dir]\n", name)
+		_, _ = fmt.Fprintf(fs.Output(), "       %s -diff manifest.json [dir|manifest.json]\n", name)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+	target := "."
+	if fs.NArg() == 1 {
+		target = fs.Arg(0)
+	}
+
+	a, err := hashes.Lookup(alg)
+	if err != nil {
+		return fail(name, err)
+	}
+	mode, err := hashtree.ParseSymlinks(symlinks)
+	if err != nil {
+		return fail(name, err)
+	}
+	opts := hashtree.Options{
+		Algorithm: a,
+		Ignore:    ignore,
+		Symlinks:  mode,
+		Workers:   workers,
+		Cache:     caching.open(name),
+	}
+
+	if against != "" {
+		code := treeDiff(name, against, target, opts, flagSet(fs, "a"))
+		if c := closeCache(name, opts.Cache); c != 0 {
+			code = c
+		}
+		return code
+	}
+
+	m, err := hashtree.Build(target, opts)
+	code := closeCache(name, opts.Cache)
+	if err != nil {
+		return fail(name, err)
+	}
+	if rootOnly {
+		fmt.Println(m.Root)
+		return code
+	}
+	if out == "" {
+		if err := m.Write(os.Stdout); err != nil {
+			return fail(name, err)
+		}
+		return code
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return fail(name, err)
+	}
+	if err := m.Write(f); err != nil {
+		_ = f.Close()
+		return fail(name, err)
+	}
+	if err := f.Close(); err != nil {
+		return fail(name, err)
+	}
+	return code
+}
+
+// treeDiff compares the saved manifest with target, which is either a
+// directory or another manifest. Unless -a was given the directory is hashed
+// with the saved manifest's algorithm.
+func treeDiff(name, saved, target string, opts hashtree.Options, explicitAlg bool) int {
+	from, err := hashtree.ReadFile(saved)
+	if err != nil {
+		return fail(name, err)
+	}
+	if err := from.Verify(); err != nil {
+		return fail(name, fmt.Errorf("%s: %w", saved, err))
+	}
+
+	var to *hashtree.Manifest
+	fi, err := os.Stat(target)
+	switch {
+	case err != nil:
+		return fail(name, err)
+	case fi.IsDir():
+		if !explicitAlg {
+			if opts.Algorithm, err = hashes.Lookup(from.Algorithm); err != nil {
+				return fail(name, err)
+			}
+		}
+		to, err = hashtree.Build(target, opts)
+	default:
+		to, err = hashtree.ReadFile(target)
+	}
+	if err != nil {
+		return fail(name, err)
+	}
+
+	changes, err := hashtree.Diff(from, to)
+	if err != nil {
+		return fail(name, err)
+	}
+	for _, c := range changes {
+		fmt.Println(c)
+	}
+	if len(changes) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// listFlag collects every value of a repeated flag.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
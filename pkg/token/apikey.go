@@ -0,0 +1,131 @@
+package token
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/schigh/str"
+	"github.com/schigh/tools/pkg/randstr"
+)
+
+const (
+	// base62 encodes both the random part of an API key and its checksum.
+	base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	// checksumLength is the number of base62 characters needed to hold a
+	// 32 bit checksum (62^6 > 2^32).
+	checksumLength = 6
+	// DefaultAPIKeyLength is the length of the random part of an API key.
+	// 30 base62 characters provide about 178 bits of entropy.
+	DefaultAPIKeyLength = 30
+	// separator joins the prefix and the body of an API key.
+	separator = "_"
+)
+
+// APIKeyOptions configure API key generation and verification.
+type APIKeyOptions struct {
+	// Prefix identifies the kind of key, such as "sk" for a secret key. It
+	// makes leaked keys easy to recognize and scan for. It must be lowercase
+	// ASCII letters and digits.
+	Prefix string
+	// Length is the number of random characters. DefaultAPIKeyLength is used
+	// when zero.
+	Length uint
+	// Polynomial selects the CRC32 polynomial, using the str.CRC32Type
+	// constants. str.CRC32TypeDefault (IEEE) is used when zero.
+	Polynomial uint32
+}
+
+// APIKey generates a key of the form <prefix>_<random><checksum>, in the
+// style of GitHub's ghp_ tokens. The checksum is the CRC32 of everything
+// before it, so a mistyped or truncated key can be rejected offline without a
+// database lookup. The checksum is not a signature: it detects accidents,
+// not forgeries.
+func APIKey(opts APIKeyOptions) (string, error) {
+	if err := validatePrefix(opts.Prefix); err != nil {
+		return "", err
+	}
+	length := opts.Length
+	if length == 0 {
+		length = DefaultAPIKeyLength
+	}
+
+	random, err := randstr.String(base62, int(length))
+	if err != nil {
+		return "", err
+	}
+	body := opts.Prefix + separator + random
+
+	sum, err := checksum(body, opts.Polynomial)
+	if err != nil {
+		return "", err
+	}
+
+	return body + sum, nil
+}
+
+// VerifyAPIKey returns an error if key is not well formed for opts or its
+// checksum does not match.
+func VerifyAPIKey(key string, opts APIKeyOptions) error {
+	if !strings.HasPrefix(key, opts.Prefix+separator) {
+		return fmt.Errorf("api key must start with '%s%s'", opts.Prefix, separator)
+	}
+	length := opts.Length
+	if length == 0 {
+		length = DefaultAPIKeyLength
+	}
+	want := len(opts.Prefix) + len(separator) + int(length) + checksumLength
+	if len(key) != want {
+		return fmt.Errorf("api key must be %d characters. got %d", want, len(key))
+	}
+
+	body, sum := key[:len(key)-checksumLength], key[len(key)-checksumLength:]
+	for i := len(opts.Prefix) + len(separator); i < len(key); i++ {
+		if strings.IndexByte(base62, key[i]) == -1 {
+			return fmt.Errorf("api key contains invalid character %q", key[i])
+		}
+	}
+
+	expected, err := checksum(body, opts.Polynomial)
+	if err != nil {
+		return err
+	}
+	if sum != expected {
+		return fmt.Errorf("api key checksum does not match")
+	}
+
+	return nil
+}
+
+// checksum returns the base62 encoded CRC32 of s. str.CRC32WithOptions
+// formats the checksum in decimal, so it is parsed back before encoding.
+func checksum(s string, polynomial uint32) (string, error) {
+	if polynomial == 0 {
+		polynomial = str.CRC32TypeDefault
+	}
+	dec := str.CRC32WithOptions(s, &str.CRC32Options{PolynomialType: polynomial})
+	v, err := strconv.ParseUint(dec, 10, 32)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse checksum '%s': %w", dec, err)
+	}
+
+	var out [checksumLength]byte
+	for i := checksumLength - 1; i >= 0; i-- {
+		out[i] = base62[v%62]
+		v /= 62
+	}
+	return string(out[:]), nil
+}
+
+func validatePrefix(prefix string) error {
+	if prefix == "" {
+		return fmt.Errorf("api key prefix is required")
+	}
+	for i := 0; i < len(prefix); i++ {
+		c := prefix[i]
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return fmt.Errorf("'%s' is an invalid api key prefix. use lowercase letters and digits", prefix)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+// Package token generates random tokens with the same options as
+// github.com/schigh/str's TokenWithOptions, but using crypto/rand and
+// rejection sampling so that tokens are suitable for secrets such as API keys
+// and session identifiers.
+//
+// str.TokenWithOptions draws from math/rand seeded with the current time,
+// which makes its output predictable to anyone who can guess roughly when a
+// token was created.
+package token
+
+import (
+	"sync"
+
+	"github.com/schigh/str"
+	"github.com/schigh/tools/pkg/randstr"
+)
+
+const (
+	// DefaultLength matches the str package's default token length.
+	DefaultLength = 20
+	// DefaultCharset matches the str package's default token charset.
+	DefaultCharset = "aAbBcCdDeEfFgGhHiIjJkKlLmMnNoOpPqQrRsStTuUvVwWxXyYzZ0123456789"
+)
+
+// Options mirrors str.TokenOptions so that existing option values can be
+// passed straight through.
+type Options = str.TokenOptions
+
+var (
+	globalLock     = &sync.RWMutex{} //nolint:gochecknoglobals
+	defaultOptions = &Options{       //nolint:gochecknoglobals
+		Length:  DefaultLength,
+		Charset: DefaultCharset,
+	}
+)
+
+// SetOptions sets the options used by New.
+func SetOptions(options *Options) {
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	defaultOptions = options
+}
+
+// New creates a token with the default options.
+func New() (string, error) {
+	globalLock.RLock()
+	opts := defaultOptions
+	globalLock.RUnlock()
+	return WithOptions(opts)
+}
+
+// WithOptions creates a token with the given options. As with
+// str.TokenWithOptions, Length includes the prefix, and a prefix that is as
+// long as Length is returned unchanged. Unlike str.TokenWithOptions, an
+// unusable charset is reported as an error rather than an empty token.
+func WithOptions(options *Options) (string, error) {
+	pl := len(options.Prefix)
+	if options.Length < 1 {
+		return "", nil
+	}
+	if pl >= int(options.Length) {
+		return options.Prefix, nil
+	}
+
+	s, err := randstr.String(options.Charset, int(options.Length)-pl)
+	if err != nil {
+		return "", err
+	}
+
+	return options.Prefix + s, nil
+}
@@ -0,0 +1,85 @@
+package typeid
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// alphabet is the lowercase Crockford base32 alphabet. It omits i, l, o and u
+// to avoid characters that are easily confused with each other.
+const alphabet = "0123456789abcdefghjkmnpqrstvwxyz"
+
+// decodeTable maps an ASCII character to its 5 bit value. Characters outside
+// of alphabet map to 0xFF. Unlike general Crockford decoding, uppercase and
+// lookalike characters are rejected because the specification only allows the
+// canonical lowercase form.
+var decodeTable = func() [256]byte { //nolint:gochecknoglobals
+	var t [256]byte
+	for i := range t {
+		t[i] = 0xFF
+	}
+	for i := 0; i < len(alphabet); i++ {
+		t[alphabet[i]] = byte(i)
+	}
+	return t
+}()
+
+// encode converts a 128 bit UUID into 26 base32 characters. The 26 characters
+// hold 130 bits, so the value is treated as having two leading zero bits and
+// the first character is therefore always in the range 0-7.
+func encode(u uuid.UUID) string {
+	var out [SuffixLength]byte
+	for i := range out {
+		out[i] = alphabet[bitsAt(u, i*5-2)]
+	}
+	return string(out[:])
+}
+
+// decode converts 26 base32 characters back into a UUID.
+func decode(s string) (uuid.UUID, error) {
+	if len(s) != SuffixLength {
+		return uuid.Nil, fmt.Errorf("typeid suffix must be %d characters. got %d", SuffixLength, len(s))
+	}
+	var u uuid.UUID
+	for i := 0; i < len(s); i++ {
+		v := decodeTable[s[i]]
+		if v == 0xFF {
+			return uuid.Nil, fmt.Errorf("typeid suffix %q contains invalid character %q", s, s[i])
+		}
+		if i == 0 && v > 7 {
+			return uuid.Nil, fmt.Errorf("typeid suffix %q overflows 128 bits; the first character must be 0-7", s)
+		}
+		setBitsAt(&u, i*5-2, v)
+	}
+
+	return u, nil
+}
+
+// bitsAt returns the 5 bits of u starting at bit offset off, counted from the
+// most significant bit. Offsets before the start of u read as zero.
+func bitsAt(u uuid.UUID, off int) byte {
+	var v byte
+	for i := 0; i < 5; i++ {
+		v <<= 1
+		pos := off + i
+		if pos < 0 {
+			continue
+		}
+		v |= (u[pos/8] >> (7 - pos%8)) & 1
+	}
+	return v
+}
+
+// setBitsAt writes the low 5 bits of v into u starting at bit offset off.
+// Offsets before the start of u are discarded.
+func setBitsAt(u *uuid.UUID, off int, v byte) {
+	for i := 0; i < 5; i++ {
+		pos := off + i
+		if pos < 0 {
+			continue
+		}
+		bit := (v >> (4 - i)) & 1
+		u[pos/8] |= bit << (7 - pos%8)
+	}
+}
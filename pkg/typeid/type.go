@@ -0,0 +1,72 @@
+package typeid
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Type binds a prefix so that IDs for one kind of entity cannot be confused
+// with another. Declare one per entity and use it in place of the package
+// level functions:
+//
+//	var UserID = typeid.MustType("user")
+//
+//	id, err := UserID.New()       // user_01h455vb4pex5vsknk084sn02q
+//	id, err = UserID.Parse(input) // rejects post_01h455vb4pex5vsknk084sn02q
+type Type struct {
+	prefix string
+}
+
+// NewType creates a Type for the given prefix.
+func NewType(prefix string) (Type, error) {
+	if err := validatePrefix(prefix); err != nil {
+		return Type{}, err
+	}
+	return Type{prefix: prefix}, nil
+}
+
+// MustType is like NewType but panics if the prefix is invalid. It is intended
+// for package level declarations.
+func MustType(prefix string) Type {
+	t, err := NewType(prefix)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Prefix returns the prefix bound to the Type.
+func (t Type) Prefix() string {
+	return t.prefix
+}
+
+// New creates a TypeID with the Type's prefix, backed by a new UUIDv7.
+func (t Type) New() (TypeID, error) {
+	return New(t.prefix)
+}
+
+// FromUUID creates a TypeID with the Type's prefix from an existing UUID.
+func (t Type) FromUUID(u uuid.UUID) TypeID {
+	return TypeID{prefix: t.prefix, uuid: u}
+}
+
+// Parse parses s and verifies that its prefix matches the Type.
+func (t Type) Parse(s string) (TypeID, error) {
+	tid, err := Parse(s)
+	if err != nil {
+		return TypeID{}, err
+	}
+	if err := t.Validate(tid); err != nil {
+		return TypeID{}, err
+	}
+	return tid, nil
+}
+
+// Validate returns an error if tid does not carry the Type's prefix.
+func (t Type) Validate(tid TypeID) error {
+	if tid.prefix != t.prefix {
+		return fmt.Errorf("typeid %q has prefix %q. expected %q", tid, tid.prefix, t.prefix)
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+// Package typeid contains a Go implementation of the TypeID specification
+// defined at https://github.com/jetify-com/typeid/tree/main/spec.
+//
+// A TypeID is a lowercase type prefix, an underscore separator and a 26
+// character Crockford base32 encoding of a UUIDv7, for example
+// user_01h455vb4pex5vsknk084sn02q. The prefix may be empty, in which case the
+// separator is omitted as well.
+package typeid
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxPrefixLength is the longest prefix allowed by the specification.
+	MaxPrefixLength = 63
+	// SuffixLength is the length of the base32 encoded UUID.
+	SuffixLength = 26
+	// separator joins the prefix and the suffix.
+	separator = '_'
+)
+
+// TypeID is a type-prefixed, UUID backed identifier. The zero value has an
+// empty prefix and the nil UUID.
+type TypeID struct {
+	prefix string
+	uuid   uuid.UUID
+}
+
+// New creates a TypeID with the given prefix, backed by a newly generated
+// UUIDv7.
+func New(prefix string) (TypeID, error) {
+	if err := validatePrefix(prefix); err != nil {
+		return TypeID{}, err
+	}
+	u, err := NewUUIDv7()
+	if err != nil {
+		return TypeID{}, err
+	}
+
+	return TypeID{prefix: prefix, uuid: u}, nil
+}
+
+// FromUUID creates a TypeID with the given prefix from an existing UUID. Any
+// UUID version is accepted, although only UUIDv7 values produce TypeIDs that
+// sort by creation time.
+func FromUUID(prefix string, u uuid.UUID) (TypeID, error) {
+	if err := validatePrefix(prefix); err != nil {
+		return TypeID{}, err
+	}
+
+	return TypeID{prefix: prefix, uuid: u}, nil
+}
+
+// FromSuffix creates a TypeID from a prefix and an already encoded suffix.
+func FromSuffix(prefix, suffix string) (TypeID, error) {
+	if err := validatePrefix(prefix); err != nil {
+		return TypeID{}, err
+	}
+	u, err := decode(suffix)
+	if err != nil {
+		return TypeID{}, err
+	}
+
+	return TypeID{prefix: prefix, uuid: u}, nil
+}
+
+// Parse attempts to create a TypeID from the given string. Parsing is strict:
+// the prefix must follow the specification's character and length rules, the
+// suffix must be exactly 26 lowercase base32 characters and the encoded value
+// must fit in 128 bits.
+func Parse(s string) (TypeID, error) {
+	idx := strings.LastIndexByte(s, separator)
+	if idx == -1 {
+		return FromSuffix("", s)
+	}
+	if idx == 0 {
+		return TypeID{}, fmt.Errorf("typeid %q has a separator but no prefix", s)
+	}
+
+	return FromSuffix(s[:idx], s[idx+1:])
+}
+
+// MustParse is like Parse but panics if the string cannot be parsed.
+func MustParse(s string) TypeID {
+	tid, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return tid
+}
+
+// Prefix returns the type prefix of the TypeID.
+func (t TypeID) Prefix() string {
+	return t.prefix
+}
+
+// Suffix returns the base32 encoded UUID portion of the TypeID.
+func (t TypeID) Suffix() string {
+	return encode(t.uuid)
+}
+
+// UUID returns the UUID encoded in the TypeID.
+func (t TypeID) UUID() uuid.UUID {
+	return t.uuid
+}
+
+// Time returns the creation time embedded in a UUIDv7 backed TypeID. The zero
+// time is returned for any other UUID version.
+func (t TypeID) Time() time.Time {
+	if t.uuid.Version() != 7 {
+		return time.Time{}
+	}
+	return UUIDv7Time(t.uuid)
+}
+
+// IsZero reports whether the TypeID is the zero value.
+func (t TypeID) IsZero() bool {
+	return t.prefix == "" && t.uuid == uuid.Nil
+}
+
+// String generates the canonical form of the TypeID.
+func (t TypeID) String() string {
+	if t.prefix == "" {
+		return t.Suffix()
+	}
+	return t.prefix + string(separator) + t.Suffix()
+}
+
+// interface impls
+
+// MarshalText implements encoding.TextMarshaler
+func (t TypeID) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (t *TypeID) UnmarshalText(text []byte) error {
+	tid, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*t = tid
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (t TypeID) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + t.String() + `"`), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (t *TypeID) UnmarshalJSON(b []byte) error {
+	lb := len(b)
+	if lb < 2 || b[0] != '"' || b[lb-1] != '"' {
+		return fmt.Errorf("typeid.TypeID.UnmarshalJSON: value must be a JSON string: %s", b)
+	}
+	if err := t.UnmarshalText(b[1 : lb-1]); err != nil {
+		return fmt.Errorf("typeid.TypeID.UnmarshalJSON: parse error: %w", err)
+	}
+
+	return nil
+}
+
+// Scan implements sql.Scanner
+func (t *TypeID) Scan(v interface{}) error {
+	if v == nil {
+		return nil
+	}
+	switch vv := v.(type) {
+	case []byte:
+		return t.UnmarshalText(vv)
+	case string:
+		return t.UnmarshalText([]byte(vv))
+	default:
+		return fmt.Errorf("typeid.TypeID.Scan: unable to convert value of type %T", v)
+	}
+}
+
+// Value implements driver.Valuer
+func (t TypeID) Value() (driver.Value, error) {
+	return t.String(), nil
+}
+
+// validatePrefix enforces the specification's prefix rules: at most 63
+// characters drawn from lowercase ASCII letters and underscores, and neither
+// starting nor ending with an underscore.
+func validatePrefix(prefix string) error {
+	if len(prefix) > MaxPrefixLength {
+		return fmt.Errorf("typeid prefix must be at most %d characters. got %d", MaxPrefixLength, len(prefix))
+	}
+	if prefix == "" {
+		return nil
+	}
+	if prefix[0] == separator || prefix[len(prefix)-1] == separator {
+		return fmt.Errorf("typeid prefix %q must not start or end with an underscore", prefix)
+	}
+	for i := 0; i < len(prefix); i++ {
+		c := prefix[i]
+		if (c < 'a' || c > 'z') && c != separator {
+			return fmt.Errorf("typeid prefix %q contains invalid character %q; only a-z and _ are allowed", prefix, c)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package typeid
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// The vendored google/uuid predates UUIDv7 support, so generation lives
+	// here.
+	v7Lock             = &sync.Mutex{} //nolint:gochecknoglobals
+	v7Random io.Reader = rand.Reader   //nolint:gochecknoglobals
+	v7Now              = time.Now      //nolint:gochecknoglobals
+)
+
+// SetRandom changes the source of randomness used for UUIDv7 generation.
+func SetRandom(r io.Reader) {
+	v7Lock.Lock()
+	defer v7Lock.Unlock()
+	v7Random = r
+}
+
+// NewUUIDv7 generates a UUIDv7 as defined by RFC 9562: a 48 bit big-endian
+// unix timestamp in milliseconds, the version and variant bits, and 74 random
+// bits.
+func NewUUIDv7() (uuid.UUID, error) {
+	v7Lock.Lock()
+	r, now := v7Random, v7Now
+	v7Lock.Unlock()
+
+	var u uuid.UUID
+	if _, err := io.ReadFull(r, u[6:]); err != nil {
+		return uuid.Nil, err
+	}
+
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(now().UnixNano()/int64(time.Millisecond)))
+	copy(u[0:6], ts[2:8])
+	u[6] = (u[6] & 0x0F) | 0x70 // version 7
+	u[8] = (u[8] & 0x3F) | 0x80 // RFC 4122 variant
+
+	return u, nil
+}
+
+// UUIDv7Time returns the millisecond timestamp stored in the first 48 bits of
+// a UUIDv7.
+func UUIDv7Time(u uuid.UUID) time.Time {
+	var ts [8]byte
+	copy(ts[2:8], u[0:6])
+	msec := int64(binary.BigEndian.Uint64(ts[:]))
+	return time.Unix(0, msec*int64(time.Millisecond))
+}